@@ -9,16 +9,25 @@
 // - Secure Data Storage with Encryption
 // - Retrieve and Manage Data via gRPC
 // - Interactive TUI using `tview`
+// - Command line subcommands such as `gophkeeper import`
+// - Named server profiles selected with `--profile`
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"time"
 
+	"github.com/golangTroshin/gophkeeper/client/internal/commands"
+	"github.com/golangTroshin/gophkeeper/client/internal/config"
 	"github.com/golangTroshin/gophkeeper/client/internal/forms"
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	"github.com/golangTroshin/gophkeeper/client/internal/keyring"
 	pb "github.com/golangTroshin/gophkeeper/grpc"
 	"github.com/rivo/tview"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // client is a global gRPC client instance used to communicate with the GophKeeper server.
@@ -30,20 +39,83 @@ var (
 	BuildDate = "unknown"
 )
 
-// main initializes the gRPC connection and starts the TUI application.
+// main selects the client profile, initializes the gRPC connection and either
+// runs the requested subcommand or starts the TUI application.
 func main() {
-	conn, err := grpc.NewClient("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	profile, args, err := parseArgs(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	handlers.RequestTimeout = time.Duration(profile.Timeouts.Request)
+	if profile.RememberSession {
+		dir, err := keyring.DefaultDir()
+		if err != nil {
+			log.Fatalf("Failed to locate the keyring: %v", err)
+		}
+		handlers.PersistSessions(keyring.Open(dir), profile.Name)
+	}
+	forms.Configure(profile)
+	commands.Configure(profile)
+
+	creds, err := profile.TLS.Credentials()
+	if err != nil {
+		log.Fatalf("Failed to configure TLS: %v", err)
+	}
+	conn, err := grpc.NewClient(profile.Server, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		log.Fatalf("Failed to connect to gRPC server: %v", err)
 	}
 	defer conn.Close()
 	client = pb.NewGophKeeperServiceClient(conn)
 
+	if len(args) > 0 {
+		if err := commands.Run(client, args); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
 	app := tview.NewApplication()
 
 	forms.ShowVersionInfo(app, client, Version, BuildDate)
 
-	if err := app.Run(); err != nil {
+	err = app.Run()
+	if closeErr := forms.Close(); closeErr != nil {
+		log.Printf("Failed to clear clipboard: %v", closeErr)
+	}
+	if err != nil {
 		log.Fatalf("Error running TUI application: %v", err)
 	}
 }
+
+// parseArgs parses the global flags preceding the subcommand and resolves the
+// client profile. It returns the remaining arguments.
+func parseArgs(args []string) (config.Profile, []string, error) {
+	var o config.Overrides
+	fs := flag.NewFlagSet("gophkeeper", flag.ContinueOnError)
+	fs.StringVar(&o.File, "config", "", "configuration file (default $XDG_CONFIG_HOME/gophkeeper/config.yaml)")
+	fs.StringVar(&o.Profile, "profile", "", "profile to use, see "+config.ProfileEnv)
+	fs.StringVar(&o.Server, "server", "", "server address, overrides the profile")
+	fs.StringVar(&o.Username, "username", "", "username suggested when logging in")
+	fs.StringVar(&o.CAFile, "ca", "", "PEM file with the CA certificates to trust, implies -tls")
+	useTLS := fs.Bool("tls", false, "connect with TLS, overrides the profile")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gophkeeper [flags] [command] [command flags]\n\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nRun 'gophkeeper help' to list the commands.")
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		return config.Profile{}, nil, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "tls" {
+			o.TLS = useTLS
+		}
+	})
+
+	profile, err := config.Resolve(o)
+	return profile, fs.Args(), err
+}
@@ -0,0 +1,244 @@
+// Package agent keeps an unlocked session in a background process, so
+// short-lived CLI commands can reuse it instead of asking for the password
+// every time.
+//
+// The agent listens on a Unix socket only the current user can access. Every
+// connection carries a single JSON Request answered by a single JSON
+// Response. The session token and the derived encryption key are only held
+// in memory and are forgotten after a period of inactivity or when a client
+// asks the agent to lock.
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/autolock"
+	"github.com/golangTroshin/gophkeeper/client/internal/socket"
+)
+
+// SocketEnv is the environment variable overriding the agent socket path.
+const SocketEnv = "GOPHKEEPER_AGENT_SOCK"
+
+// Request methods.
+const (
+	MethodStatus = "status" // Report whether the agent is locked
+	MethodGet    = "get"    // Return the session
+	MethodStore  = "store"  // Keep a new session
+	MethodLock   = "lock"   // Forget the session
+)
+
+// ioTimeout bounds a single request so a stuck client cannot block the agent.
+const ioTimeout = 5 * time.Second
+
+// ErrLocked is returned when the agent holds no session.
+var ErrLocked = errors.New("agent is locked")
+
+// ErrNotRunning is returned when no agent listens on the socket.
+var ErrNotRunning = errors.New("agent is not running")
+
+// Session is an unlocked vault session.
+type Session struct {
+	Username string `json:"username"`
+	Token    string `json:"token"`
+	Key      []byte `json:"key"`
+}
+
+// Wipe overwrites the encryption key.
+func (s *Session) Wipe() {
+	for i := range s.Key {
+		s.Key[i] = 0
+	}
+}
+
+// Request is sent by clients.
+type Request struct {
+	Method  string   `json:"method"`
+	Session *Session `json:"session,omitempty"` // For MethodStore
+}
+
+// Response is sent by the agent.
+type Response struct {
+	Error    string   `json:"error,omitempty"`
+	Locked   bool     `json:"locked"`
+	Username string   `json:"username,omitempty"`
+	Session  *Session `json:"session,omitempty"` // For MethodGet
+}
+
+// Agent holds a session until it is locked.
+type Agent struct {
+	locker *autolock.Locker
+
+	mu      sync.Mutex
+	session *Session
+}
+
+// New creates a locked agent that locks again after timeout without
+// requests. A zero timeout keeps the session until an explicit lock.
+func New(timeout time.Duration) *Agent {
+	a := &Agent{}
+	a.locker = autolock.New(timeout, a.forget)
+	return a
+}
+
+// Lock forgets the session.
+func (a *Agent) Lock() {
+	a.locker.Lock()
+}
+
+// forget wipes and drops the session.
+func (a *Agent) forget() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.session != nil {
+		a.session.Wipe()
+		a.session = nil
+	}
+}
+
+// Handle answers a single request.
+func (a *Agent) Handle(req Request) Response {
+	switch req.Method {
+	case MethodStatus:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.session == nil {
+			return Response{Locked: true}
+		}
+		return Response{Username: a.session.Username}
+	case MethodGet:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.session == nil {
+			return Response{Locked: true, Error: ErrLocked.Error()}
+		}
+		a.locker.Touch()
+		s := *a.session
+		s.Key = append([]byte(nil), a.session.Key...)
+		return Response{Username: s.Username, Session: &s}
+	case MethodStore:
+		s := req.Session
+		if s == nil || s.Username == "" || s.Token == "" {
+			return Response{Error: "session is incomplete"}
+		}
+		a.mu.Lock()
+		if a.session != nil {
+			a.session.Wipe()
+		}
+		a.session = s
+		a.mu.Unlock()
+		a.locker.Start()
+		return Response{Username: s.Username}
+	case MethodLock:
+		a.Lock()
+		return Response{Locked: true}
+	default:
+		return Response{Error: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+}
+
+// Serve accepts connections until the listener is closed.
+func (a *Agent) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go a.serveConn(conn)
+	}
+}
+
+// serveConn answers the request of a single connection.
+func (a *Agent) serveConn(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		_ = json.NewEncoder(conn).Encode(Response{Error: "malformed request"})
+		return
+	}
+	resp := a.Handle(req)
+	_ = json.NewEncoder(conn).Encode(resp)
+	if resp.Session != nil {
+		resp.Session.Wipe()
+	}
+}
+
+// Client talks to an agent.
+type Client struct {
+	path string
+}
+
+// NewClient creates a client for the agent listening on path.
+func NewClient(path string) *Client {
+	return &Client{path: path}
+}
+
+// Status reports whether the agent is locked and, if not, whose session it holds.
+func (c *Client) Status() (locked bool, username string, err error) {
+	resp, err := c.call(Request{Method: MethodStatus})
+	if err != nil {
+		return false, "", err
+	}
+	return resp.Locked, resp.Username, nil
+}
+
+// Get returns the agent's session. The caller should wipe it after use.
+func (c *Client) Get() (*Session, error) {
+	resp, err := c.call(Request{Method: MethodGet})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Session == nil {
+		return nil, ErrLocked
+	}
+	return resp.Session, nil
+}
+
+// Store hands a session to the agent.
+func (c *Client) Store(s *Session) error {
+	_, err := c.call(Request{Method: MethodStore, Session: s})
+	return err
+}
+
+// Lock asks the agent to forget its session.
+func (c *Client) Lock() error {
+	_, err := c.call(Request{Method: MethodLock})
+	return err
+}
+
+// call sends a request and waits for the response.
+func (c *Client) call(req Request) (Response, error) {
+	conn, err := socket.Dial(c.path, ioTimeout)
+	if errors.Is(err, socket.ErrInsecure) {
+		return Response{}, err // Never talk to an agent another user may control
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("invalid agent response: %w", err)
+	}
+	switch {
+	case resp.Error == ErrLocked.Error():
+		return resp, ErrLocked
+	case resp.Error != "":
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/socket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socketPath returns a socket path in a directory only the current user can access.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "private")
+	require.NoError(t, os.Mkdir(dir, 0700))
+	return filepath.Join(dir, "agent.sock")
+}
+
+// startAgent serves a over a Unix socket and returns a client for it.
+func startAgent(t *testing.T, a *Agent) *Client {
+	t.Helper()
+	path := socketPath(t)
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go a.Serve(listener)
+	return NewClient(path)
+}
+
+// testSession returns a session with a recognizable key
+func testSession() *Session {
+	return &Session{Username: "alice", Token: "mock_token", Key: []byte{1, 2, 3, 4}}
+}
+
+// TestAgentSession ensures a stored session is returned until locked
+func TestAgentSession(t *testing.T) {
+	c := startAgent(t, New(time.Minute))
+
+	locked, _, err := c.Status()
+	require.NoError(t, err)
+	assert.True(t, locked, "A new agent should be locked")
+	_, err = c.Get()
+	assert.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, c.Store(testSession()))
+	locked, username, err := c.Status()
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.Equal(t, "alice", username)
+
+	s, err := c.Get()
+	require.NoError(t, err)
+	assert.Equal(t, testSession(), s)
+
+	require.NoError(t, c.Lock())
+	_, err = c.Get()
+	assert.ErrorIs(t, err, ErrLocked)
+}
+
+// TestAgentTimeout ensures the session is forgotten after inactivity
+func TestAgentTimeout(t *testing.T) {
+	a := New(50 * time.Millisecond)
+	c := startAgent(t, a)
+
+	s := testSession()
+	a.Handle(Request{Method: MethodStore, Session: s})
+	assert.Eventually(t, func() bool {
+		locked, _, err := c.Status() // Status does not count as activity
+		return err == nil && locked
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []byte{0, 0, 0, 0}, s.Key, "The key should be wiped when locking")
+}
+
+// TestAgentInvalidRequests ensures incomplete sessions and unknown methods are rejected
+func TestAgentInvalidRequests(t *testing.T) {
+	c := startAgent(t, New(time.Minute))
+	assert.Error(t, c.Store(&Session{Username: "alice"}))
+
+	_, err := c.call(Request{Method: "dump"})
+	assert.ErrorContains(t, err, "unknown method")
+}
+
+// TestClientNotRunning ensures a missing agent is reported
+func TestClientNotRunning(t *testing.T) {
+	_, err := NewClient(socketPath(t)).Get()
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
+
+// TestClientInsecureDir ensures the session is never sent to a socket in a
+// directory other users can access
+func TestClientInsecureDir(t *testing.T) {
+	path := socketPath(t)
+	require.NoError(t, os.Chmod(filepath.Dir(path), 0755))
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go New(0).Serve(listener)
+
+	err = NewClient(path).Store(testSession())
+	assert.ErrorIs(t, err, socket.ErrInsecure)
+	assert.NotErrorIs(t, err, ErrNotRunning, "An insecure socket must not look like a stopped agent")
+}
@@ -0,0 +1,209 @@
+// Package archive implements the portable GophKeeper backup format.
+//
+// An archive is a JSON document with a small plaintext header describing the
+// format version and key derivation parameters, followed by the vault
+// contents compressed with gzip and encrypted with AES-GCM using a key derived
+// from the archive password. Archives are independent of the master seed, so
+// they can be restored into any account.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// FormatName identifies GophKeeper archives.
+const FormatName = "gophkeeper-archive"
+
+// Version is the current archive format version.
+const Version = 1
+
+// Key derivation parameters for new archives.
+const (
+	kdfName       = "pbkdf2-sha256"
+	kdfIterations = 600000
+	saltSize      = 16
+	keySize       = 32
+)
+
+// ErrWrongPassword is returned when an archive cannot be decrypted.
+var ErrWrongPassword = errors.New("wrong archive password or corrupted archive")
+
+// Item is a single decrypted vault item.
+type Item struct {
+	Type     string            `json:"type"`     // Data type name, e.g. "CREDENTIALS"
+	Metadata string            `json:"metadata"` // Item description
+	Fields   map[string]string `json:"fields"`   // Item fields including binary attachments
+}
+
+// Vault is the archive payload.
+type Vault struct {
+	ExportedAt time.Time `json:"exported_at"`
+	Items      []Item    `json:"items"`
+}
+
+// kdf describes how the archive key is derived from the password.
+type kdf struct {
+	Name       string `json:"name"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+}
+
+// envelope is the on-disk representation of an encrypted archive.
+type envelope struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        kdf    `json:"kdf"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Seal writes the vault to w as a password protected archive.
+func Seal(w io.Writer, vault *Vault, password string) error {
+	if password == "" {
+		return fmt.Errorf("archive password cannot be empty")
+	}
+
+	var payload bytes.Buffer
+	zw := gzip.NewWriter(&payload)
+	if err := json.NewEncoder(zw).Encode(vault); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	env := envelope{
+		Format:  FormatName,
+		Version: Version,
+		KDF:     kdf{Name: kdfName, Iterations: kdfIterations, Salt: make([]byte, saltSize)},
+	}
+	if _, err := io.ReadFull(rand.Reader, env.KDF.Salt); err != nil {
+		return err
+	}
+
+	aesGCM, err := newCipher(password, env.KDF)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, env.Nonce); err != nil {
+		return err
+	}
+	env.Ciphertext = aesGCM.Seal(nil, env.Nonce, payload.Bytes(), env.additionalData())
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(env)
+}
+
+// Open reads and decrypts an archive written by Seal.
+func Open(r io.Reader, password string) (*Vault, error) {
+	var env envelope
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	if env.Format != FormatName {
+		return nil, fmt.Errorf("not a GophKeeper archive")
+	}
+	if env.Version > Version {
+		return nil, fmt.Errorf("archive version %d is newer than supported version %d", env.Version, Version)
+	}
+	if env.KDF.Name != kdfName {
+		return nil, fmt.Errorf("unsupported key derivation %q", env.KDF.Name)
+	}
+
+	aesGCM, err := newCipher(password, env.KDF)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aesGCM.NonceSize() {
+		return nil, ErrWrongPassword
+	}
+	payload, err := aesGCM.Open(nil, env.Nonce, env.Ciphertext, env.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var vault Vault
+	if err := json.NewDecoder(zr).Decode(&vault); err != nil {
+		return nil, fmt.Errorf("invalid archive payload: %w", err)
+	}
+	return &vault, nil
+}
+
+// WritePlainJSON writes the vault as unencrypted, indented JSON.
+func WritePlainJSON(w io.Writer, vault *Vault) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vault)
+}
+
+// WritePlainCSV writes the vault as an unencrypted CSV table with one column per field name.
+func WritePlainCSV(w io.Writer, vault *Vault) error {
+	seen := make(map[string]bool)
+	var names []string
+	for _, item := range vault.Items {
+		for name := range item.Fields {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"type", "metadata"}, names...)); err != nil {
+		return err
+	}
+	for _, item := range vault.Items {
+		row := []string{item.Type, item.Metadata}
+		for _, name := range names {
+			row = append(row, item.Fields[name])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// newCipher derives the archive key and returns an AES-GCM instance.
+func newCipher(password string, params kdf) (cipher.AEAD, error) {
+	if params.Iterations <= 0 || len(params.Salt) == 0 {
+		return nil, fmt.Errorf("invalid key derivation parameters")
+	}
+	key := pbkdf2.Key([]byte(password), params.Salt, params.Iterations, keySize, sha256.New)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the plaintext header to the ciphertext.
+func (e *envelope) additionalData() []byte {
+	return []byte(fmt.Sprintf("%s|%d|%s|%d|%x", e.Format, e.Version, e.KDF.Name, e.KDF.Iterations, e.KDF.Salt))
+}
@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testVault() *Vault {
+	return &Vault{
+		ExportedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Items: []Item{
+			{Type: "CREDENTIALS", Metadata: "GitHub", Fields: map[string]string{"login": "alice", "password": "s3cret"}},
+			{Type: "BINARY", Metadata: "Key file", Fields: map[string]string{"file_path": "id.key", "file_data": "\x01\x02"}},
+		},
+	}
+}
+
+// TestSealOpenRoundTrip ensures an archive can be decrypted with the same password
+func TestSealOpenRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Seal(&buf, testVault(), "archive-pass"))
+	assert.NotContains(t, buf.String(), "s3cret", "Archive should not contain plaintext secrets")
+
+	vault, err := Open(&buf, "archive-pass")
+	require.NoError(t, err)
+	assert.Equal(t, testVault(), vault)
+}
+
+// TestOpenWrongPassword ensures a wrong password is reported
+func TestOpenWrongPassword(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Seal(&buf, testVault(), "archive-pass"))
+
+	_, err := Open(&buf, "other-pass")
+	assert.ErrorIs(t, err, ErrWrongPassword)
+}
+
+// TestOpenTamperedHeader ensures header fields are authenticated
+func TestOpenTamperedHeader(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Seal(&buf, testVault(), "archive-pass"))
+
+	var env envelope
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &env))
+	env.Version = 0
+	tampered, err := json.Marshal(env)
+	require.NoError(t, err)
+
+	_, err = Open(bytes.NewReader(tampered), "archive-pass")
+	assert.ErrorIs(t, err, ErrWrongPassword)
+}
+
+// TestOpenNewerVersion ensures archives from future versions are rejected
+func TestOpenNewerVersion(t *testing.T) {
+	_, err := Open(strings.NewReader(`{"format":"gophkeeper-archive","version":99}`), "pass")
+	assert.ErrorContains(t, err, "newer")
+
+	_, err = Open(strings.NewReader(`{"format":"something-else"}`), "pass")
+	assert.Error(t, err)
+}
+
+// TestSealEmptyPassword ensures archives always have a password
+func TestSealEmptyPassword(t *testing.T) {
+	assert.Error(t, Seal(&bytes.Buffer{}, testVault(), ""))
+}
+
+// TestWritePlainCSV ensures every field name becomes a column
+func TestWritePlainCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WritePlainCSV(&buf, testVault()))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "type,metadata,file_data,file_path,login,password", lines[0])
+	assert.Equal(t, "CREDENTIALS,GitHub,,,alice,s3cret", lines[1])
+}
@@ -0,0 +1,105 @@
+// Package autolock locks the client after a period of inactivity.
+package autolock
+
+import (
+	"sync"
+	"time"
+)
+
+// Locker calls a lock function once no activity was reported for the timeout.
+type Locker struct {
+	timeout time.Duration
+	onLock  func()
+
+	mu     sync.Mutex
+	timer  *time.Timer
+	active bool // Between Start and Stop or a lock, even without a timer
+}
+
+// New creates a stopped locker. A zero timeout disables automatic locking,
+// but Lock still works.
+func New(timeout time.Duration, onLock func()) *Locker {
+	return &Locker{timeout: timeout, onLock: onLock}
+}
+
+// Timeout returns the inactivity timeout.
+func (l *Locker) Timeout() time.Duration {
+	return l.timeout
+}
+
+// Start arms the inactivity timer, e.g. after login.
+func (l *Locker) Start() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active = true
+	l.arm()
+}
+
+// Touch reports activity and restarts the timer if it is running.
+func (l *Locker) Touch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timer != nil {
+		l.arm()
+	}
+}
+
+// Stop disarms the timer without locking, e.g. on logout.
+func (l *Locker) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active = false
+	l.disarm()
+}
+
+// Active reports whether there is a session to lock: the locker was started
+// and has not been stopped or locked since. Unlike Running it does not
+// depend on the timeout.
+func (l *Locker) Active() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active
+}
+
+// Running reports whether the timer is armed.
+func (l *Locker) Running() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.timer != nil
+}
+
+// Lock disarms the timer and locks immediately.
+func (l *Locker) Lock() {
+	l.Stop()
+	l.onLock()
+}
+
+// arm (re)starts the timer. The caller holds mu.
+func (l *Locker) arm() {
+	l.disarm()
+	if l.timeout <= 0 {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(l.timeout, func() {
+		l.mu.Lock()
+		current := l.timer == timer
+		if current {
+			l.timer = nil
+			l.active = false
+		}
+		l.mu.Unlock()
+		if current {
+			l.onLock()
+		}
+	})
+	l.timer = timer
+}
+
+// disarm stops the timer. The caller holds mu.
+func (l *Locker) disarm() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+}
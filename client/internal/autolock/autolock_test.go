@@ -0,0 +1,72 @@
+package autolock
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLockAfterInactivity ensures the lock function runs once the timeout passes
+func TestLockAfterInactivity(t *testing.T) {
+	var locks atomic.Int32
+	l := New(50*time.Millisecond, func() { locks.Add(1) })
+
+	time.Sleep(80 * time.Millisecond)
+	assert.Zero(t, locks.Load(), "A stopped locker should not lock")
+
+	l.Start()
+	assert.True(t, l.Running())
+	assert.Eventually(t, func() bool { return locks.Load() == 1 }, time.Second, 10*time.Millisecond)
+	assert.False(t, l.Running(), "The timer should be disarmed after locking")
+	assert.False(t, l.Active(), "Locking ends the session")
+}
+
+// TestTouchDelaysLock ensures activity restarts the timeout
+func TestTouchDelaysLock(t *testing.T) {
+	var locks atomic.Int32
+	l := New(80*time.Millisecond, func() { locks.Add(1) })
+	l.Start()
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(40 * time.Millisecond)
+		l.Touch()
+	}
+	assert.Zero(t, locks.Load(), "Activity should keep the client unlocked")
+
+	l.Stop()
+	time.Sleep(120 * time.Millisecond)
+	assert.Zero(t, locks.Load(), "A stopped locker should not lock")
+
+	l.Touch()
+	assert.False(t, l.Running(), "Touch should not start a stopped locker")
+}
+
+// TestManualLock ensures Lock works with automatic locking disabled
+func TestManualLock(t *testing.T) {
+	var locks atomic.Int32
+	l := New(0, func() { locks.Add(1) })
+	l.Start()
+	assert.False(t, l.Running(), "A zero timeout disables the timer")
+
+	l.Lock()
+	assert.Equal(t, int32(1), locks.Load())
+}
+
+// TestActiveWithoutTimeout ensures a session counts as active with automatic
+// locking disabled, so the lock shortcut still works
+func TestActiveWithoutTimeout(t *testing.T) {
+	l := New(0, func() {})
+	assert.False(t, l.Active(), "A new locker has no session")
+
+	l.Start()
+	assert.True(t, l.Active())
+
+	l.Lock()
+	assert.False(t, l.Active(), "Locking ends the session")
+
+	l.Start()
+	l.Stop()
+	assert.False(t, l.Active(), "Stopping ends the session")
+}
@@ -0,0 +1,383 @@
+// Package card validates payment cards stored as CARD vault items.
+//
+// Card numbers are checked with the Luhn algorithm and their brand is
+// detected from the issuer prefix, which also determines the expected
+// number and security code lengths. Expiration dates use the MM/YY format
+// printed on cards; a card is valid until the end of its expiration month.
+package card
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// Item field names.
+const (
+	FieldNumber         = "card_number"
+	FieldExpiration     = "expiration_date"
+	FieldCVV            = "cvv"
+	FieldHolder         = "cardholder"
+	FieldPIN            = "pin"
+	FieldBillingAddress = "billing_address"
+	FieldBrand          = "brand"
+)
+
+// Brand is a card network.
+type Brand string
+
+// Detected card brands.
+const (
+	BrandUnknown    Brand = "Unknown"
+	BrandVisa       Brand = "Visa"
+	BrandMastercard Brand = "Mastercard"
+	BrandAmex       Brand = "American Express"
+	BrandDiscover   Brand = "Discover"
+	BrandJCB        Brand = "JCB"
+	BrandDiners     Brand = "Diners Club"
+	BrandUnionPay   Brand = "UnionPay"
+	BrandMaestro    Brand = "Maestro"
+	BrandMir        Brand = "Mir"
+)
+
+// brandRule matches card numbers whose prefix of the given length is in [from, to].
+type brandRule struct {
+	brand    Brand
+	digits   int
+	from, to int
+	lengths  []int
+}
+
+// brandRules are checked in order, so narrower ranges come first.
+var brandRules = []brandRule{
+	{BrandAmex, 2, 34, 34, []int{15}},
+	{BrandAmex, 2, 37, 37, []int{15}},
+	{BrandDiners, 3, 300, 305, []int{14, 16, 19}},
+	{BrandDiners, 2, 36, 36, []int{14, 16, 19}},
+	{BrandDiners, 2, 38, 39, []int{14, 16, 19}},
+	{BrandJCB, 4, 3528, 3589, []int{16, 17, 18, 19}},
+	{BrandDiscover, 4, 6011, 6011, []int{16, 19}},
+	{BrandDiscover, 3, 644, 649, []int{16, 19}},
+	{BrandDiscover, 2, 65, 65, []int{16, 19}},
+	{BrandUnionPay, 2, 62, 62, []int{16, 17, 18, 19}},
+	{BrandMir, 4, 2200, 2204, []int{16, 17, 18, 19}},
+	{BrandMastercard, 4, 2221, 2720, []int{16}},
+	{BrandMastercard, 2, 51, 55, []int{16}},
+	{BrandMaestro, 2, 50, 50, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{BrandMaestro, 2, 56, 69, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{BrandVisa, 1, 4, 4, []int{13, 16, 19}},
+}
+
+// Normalize removes spaces and dashes from a card number.
+func Normalize(number string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(number))
+}
+
+// Luhn reports whether a normalized card number has a valid check digit.
+func Luhn(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// DetectBrand returns the brand of a card number from its prefix.
+func DetectBrand(number string) Brand {
+	if rule, ok := matchBrand(Normalize(number)); ok {
+		return rule.brand
+	}
+	return BrandUnknown
+}
+
+// matchBrand finds the first rule matching the number prefix.
+func matchBrand(number string) (brandRule, bool) {
+	for _, rule := range brandRules {
+		if len(number) < rule.digits {
+			continue
+		}
+		prefix, err := strconv.Atoi(number[:rule.digits])
+		if err != nil {
+			return brandRule{}, false
+		}
+		if prefix >= rule.from && prefix <= rule.to {
+			return rule, true
+		}
+	}
+	return brandRule{}, false
+}
+
+// CVVLength returns the length of the security code for the brand.
+func (b Brand) CVVLength() int {
+	if b == BrandAmex {
+		return 4
+	}
+	return 3
+}
+
+// Expiry is the month a card expires.
+type Expiry struct {
+	Month int // 1-12
+	Year  int // Four-digit year
+}
+
+// ParseExpiry parses an expiration date in MM/YY or MM/YYYY format.
+func ParseExpiry(s string) (Expiry, error) {
+	month, year, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Expiry{}, fmt.Errorf("expiration date %q is not in MM/YY format", s)
+	}
+	m, err := strconv.Atoi(strings.TrimSpace(month))
+	if err != nil || m < 1 || m > 12 {
+		return Expiry{}, fmt.Errorf("invalid expiration month %q", month)
+	}
+
+	year = strings.TrimSpace(year)
+	y, err := strconv.Atoi(year)
+	if err != nil || (len(year) != 2 && len(year) != 4) {
+		return Expiry{}, fmt.Errorf("invalid expiration year %q", year)
+	}
+	if len(year) == 2 {
+		y += 2000
+	}
+	return Expiry{Month: m, Year: y}, nil
+}
+
+// String formats the expiry as MM/YY.
+func (e Expiry) String() string {
+	return fmt.Sprintf("%02d/%02d", e.Month, e.Year%100)
+}
+
+// End returns the first instant after the expiration month, in the location of now.
+func (e Expiry) End(loc *time.Location) time.Time {
+	return time.Date(e.Year, time.Month(e.Month)+1, 1, 0, 0, 0, 0, loc)
+}
+
+// Expired reports whether the card can no longer be used at now.
+func (e Expiry) Expired(now time.Time) bool {
+	return !now.Before(e.End(now.Location()))
+}
+
+// ExpiresWithin reports whether the card expires within d of now, including expired cards.
+func (e Expiry) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !now.Add(d).Before(e.End(now.Location()))
+}
+
+// Card is a payment card as stored in the vault.
+type Card struct {
+	Number         string
+	Expiry         Expiry
+	CVV            string
+	Holder         string
+	PIN            string
+	BillingAddress string
+}
+
+// FromFields parses and validates card fields entered by the user.
+// Number separators are removed and the expiration date is normalized to MM/YY.
+func FromFields(fields map[string]string) (*Card, error) {
+	c := &Card{
+		Number:         Normalize(fields[FieldNumber]),
+		CVV:            strings.TrimSpace(fields[FieldCVV]),
+		Holder:         strings.TrimSpace(fields[FieldHolder]),
+		PIN:            strings.TrimSpace(fields[FieldPIN]),
+		BillingAddress: strings.TrimSpace(fields[FieldBillingAddress]),
+	}
+	expiry, err := ParseExpiry(fields[FieldExpiration])
+	if err != nil {
+		return nil, err
+	}
+	c.Expiry = expiry
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseItem restores a card from the decrypted data of a CARD item without
+// validating it, so cards saved before validation existed can still be shown.
+func ParseItem(data []byte) (*Card, error) {
+	var fields map[string]string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid card item: %w", err)
+	}
+	expiry, err := ParseExpiry(fields[FieldExpiration])
+	if err != nil {
+		return nil, err
+	}
+	return &Card{
+		Number:         Normalize(fields[FieldNumber]),
+		Expiry:         expiry,
+		CVV:            fields[FieldCVV],
+		Holder:         fields[FieldHolder],
+		PIN:            fields[FieldPIN],
+		BillingAddress: fields[FieldBillingAddress],
+	}, nil
+}
+
+// Validate checks the number, security code and PIN.
+func (c *Card) Validate() error {
+	if c.Number == "" {
+		return fmt.Errorf("card number is required")
+	}
+	if !isDigits(c.Number) {
+		return fmt.Errorf("card number must contain only digits")
+	}
+	if len(c.Number) < 12 || len(c.Number) > 19 {
+		return fmt.Errorf("card number must have 12 to 19 digits, got %d", len(c.Number))
+	}
+	if !Luhn(c.Number) {
+		return fmt.Errorf("card number failed the checksum, check for typos")
+	}
+
+	brand := c.Brand()
+	if rule, ok := matchBrand(c.Number); ok && !containsInt(rule.lengths, len(c.Number)) {
+		return fmt.Errorf("%s card numbers cannot have %d digits", brand, len(c.Number))
+	}
+	if c.CVV != "" && (!isDigits(c.CVV) || len(c.CVV) != brand.CVVLength()) {
+		return fmt.Errorf("%s security code must have %d digits", brand, brand.CVVLength())
+	}
+	if c.PIN != "" && (!isDigits(c.PIN) || len(c.PIN) < 4 || len(c.PIN) > 12) {
+		return fmt.Errorf("PIN must have 4 to 12 digits")
+	}
+	return nil
+}
+
+// Brand returns the detected brand of the card.
+func (c *Card) Brand() Brand {
+	return DetectBrand(c.Number)
+}
+
+// Masked returns the card number with all but the last four digits hidden.
+func (c *Card) Masked() string {
+	if len(c.Number) <= 4 {
+		return c.Number
+	}
+	return group(strings.Repeat("•", len(c.Number)-4)+c.Number[len(c.Number)-4:], c.Brand())
+}
+
+// Format groups a card number for display, 4-6-5 for American Express and
+// groups of four otherwise.
+func Format(number string) string {
+	return group(number, DetectBrand(number))
+}
+
+// group splits a possibly masked card number into the groups used by the brand.
+func group(number string, brand Brand) string {
+	runes := []rune(number)
+	groups := []int{4, 4, 4, 4, 4}
+	if len(runes) == 15 && brand == BrandAmex {
+		groups = []int{4, 6, 5}
+	}
+
+	var parts []string
+	for _, size := range groups {
+		if len(runes) == 0 {
+			break
+		}
+		if size > len(runes) {
+			size = len(runes)
+		}
+		parts = append(parts, string(runes[:size]))
+		runes = runes[size:]
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Fields returns the item fields stored in the vault.
+func (c *Card) Fields() map[string]string {
+	return map[string]string{
+		FieldNumber:         c.Number,
+		FieldExpiration:     c.Expiry.String(),
+		FieldCVV:            c.CVV,
+		FieldHolder:         c.Holder,
+		FieldPIN:            c.PIN,
+		FieldBillingAddress: c.BillingAddress,
+		FieldBrand:          string(c.Brand()),
+	}
+}
+
+// Expiring is a card that expires soon or has expired.
+type Expiring struct {
+	Metadata string
+	Card     *Card
+}
+
+// String describes when the card expires.
+func (e Expiring) String(now time.Time) string {
+	state := "expires"
+	if e.Card.Expiry.Expired(now) {
+		state = "expired"
+	}
+	return fmt.Sprintf("%s %s %s (%s)", e.Card.Brand(), e.Card.Masked(), state, e.Card.Expiry)
+}
+
+// ExpiringCards returns the cards among decrypted CARD items that expire
+// within d of now, soonest first. Items that cannot be parsed are skipped.
+func ExpiringCards(items []*pb.DataItem, now time.Time, d time.Duration) []Expiring {
+	var expiring []Expiring
+	for _, item := range items {
+		if item.DataType != pb.DataType_CARD {
+			continue
+		}
+		c, err := ParseItem(item.Data)
+		if err != nil {
+			continue
+		}
+		if c.Expiry.ExpiresWithin(now, d) {
+			expiring = append(expiring, Expiring{Metadata: item.Metadata, Card: c})
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].Card.Expiry.End(time.UTC).Before(expiring[j].Card.Expiry.End(time.UTC))
+	})
+	return expiring
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// containsInt reports whether values contains v.
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package card
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLuhn ensures check digits are verified
+func TestLuhn(t *testing.T) {
+	assert.True(t, Luhn("4111111111111111"))
+	assert.True(t, Luhn("378282246310005"))
+	assert.False(t, Luhn("4111111111111112"), "A single typo should be detected")
+	assert.False(t, Luhn("4111-1111"))
+	assert.False(t, Luhn("0"))
+}
+
+// TestDetectBrand ensures brands are detected from issuer prefixes
+func TestDetectBrand(t *testing.T) {
+	tests := map[string]Brand{
+		"4111 1111 1111 1111": BrandVisa,
+		"5555555555554444":    BrandMastercard,
+		"2223003122003222":    BrandMastercard,
+		"378282246310005":     BrandAmex,
+		"6011111111111117":    BrandDiscover,
+		"3530111333300000":    BrandJCB,
+		"30569309025904":      BrandDiners,
+		"6200000000000005":    BrandUnionPay,
+		"2200123456789010":    BrandMir,
+		"9999999999999995":    BrandUnknown,
+	}
+	for number, brand := range tests {
+		assert.Equal(t, brand, DetectBrand(number), number)
+	}
+}
+
+// TestParseExpiry ensures MM/YY and MM/YYYY dates are accepted
+func TestParseExpiry(t *testing.T) {
+	e, err := ParseExpiry("3/27")
+	require.NoError(t, err)
+	assert.Equal(t, Expiry{Month: 3, Year: 2027}, e)
+	assert.Equal(t, "03/27", e.String())
+
+	e, err = ParseExpiry(" 12 / 2030 ")
+	require.NoError(t, err)
+	assert.Equal(t, Expiry{Month: 12, Year: 2030}, e)
+
+	for _, invalid := range []string{"", "1227", "13/27", "00/27", "01/7", "01/207", "ab/cd"} {
+		_, err := ParseExpiry(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// TestExpired ensures cards are valid until the end of the expiration month
+func TestExpired(t *testing.T) {
+	e := Expiry{Month: 2, Year: 2027}
+	assert.False(t, e.Expired(time.Date(2027, 2, 28, 23, 59, 0, 0, time.UTC)))
+	assert.True(t, e.Expired(time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+	now := time.Date(2027, 1, 15, 0, 0, 0, 0, time.UTC)
+	assert.False(t, e.ExpiresWithin(now, 30*24*time.Hour))
+	assert.True(t, e.ExpiresWithin(now, 45*24*time.Hour))
+}
+
+// TestFromFields ensures cards are validated and normalized
+func TestFromFields(t *testing.T) {
+	c, err := FromFields(map[string]string{
+		FieldNumber:     "3782 822463 10005",
+		FieldExpiration: "7/2029",
+		FieldCVV:        "1234",
+		FieldHolder:     " JANE DOE ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "378282246310005", c.Number)
+	assert.Equal(t, "JANE DOE", c.Holder)
+	assert.Equal(t, "07/29", c.Fields()[FieldExpiration])
+	assert.Equal(t, string(BrandAmex), c.Fields()[FieldBrand])
+
+	tests := map[string]map[string]string{
+		"checksum":    {FieldNumber: "4111111111111112", FieldExpiration: "01/30"},
+		"amex cvv":    {FieldNumber: "378282246310005", FieldExpiration: "01/30", FieldCVV: "123"},
+		"visa cvv":    {FieldNumber: "4111111111111111", FieldExpiration: "01/30", FieldCVV: "1234"},
+		"pin":         {FieldNumber: "4111111111111111", FieldExpiration: "01/30", FieldPIN: "12"},
+		"letters":     {FieldNumber: "4111x11111111111", FieldExpiration: "01/30"},
+		"length":      {FieldNumber: "4111111111111111111111", FieldExpiration: "01/30"},
+		"brand len":   {FieldNumber: "5555555555554", FieldExpiration: "01/30"},
+		"no expiry":   {FieldNumber: "4111111111111111"},
+		"no number":   {FieldExpiration: "01/30"},
+		"bad expiry":  {FieldNumber: "4111111111111111", FieldExpiration: "2030-01"},
+		"month range": {FieldNumber: "4111111111111111", FieldExpiration: "13/30"},
+	}
+	for name, fields := range tests {
+		_, err := FromFields(fields)
+		assert.Error(t, err, name)
+	}
+}
+
+// TestMasked ensures only the last four digits are shown
+func TestMasked(t *testing.T) {
+	visa := &Card{Number: "4111111111111111"}
+	assert.Equal(t, "•••• •••• •••• 1111", visa.Masked())
+
+	amex := &Card{Number: "378282246310005"}
+	assert.Equal(t, "3782 822463 10005", Format(amex.Number))
+	assert.Equal(t, "•••• •••••• •0005", amex.Masked())
+}
+
+// TestExpiringCards ensures the report lists expired and soon expiring cards first
+func TestExpiringCards(t *testing.T) {
+	item := func(name, expiry string) *pb.DataItem {
+		data, err := json.Marshal(map[string]string{FieldNumber: "4111111111111111", FieldExpiration: expiry})
+		require.NoError(t, err)
+		return &pb.DataItem{DataType: pb.DataType_CARD, Metadata: name, Data: data}
+	}
+	items := []*pb.DataItem{
+		item("later", "12/30"),
+		item("soon", "11/26"),
+		item("expired", "01/26"),
+		item("broken", "soon"),
+		{DataType: pb.DataType_TEXT, Metadata: "note", Data: []byte(`{"text":"x"}`)},
+	}
+
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	expiring := ExpiringCards(items, now, 60*24*time.Hour)
+	require.Len(t, expiring, 2)
+	assert.Equal(t, "expired", expiring[0].Metadata)
+	assert.Contains(t, expiring[0].String(now), "expired (01/26)")
+	assert.Equal(t, "soon", expiring[1].Metadata)
+	assert.Contains(t, expiring[1].String(now), "expires (11/26)")
+}
@@ -0,0 +1,217 @@
+// Package clipboard copies secrets to the system clipboard and clears them
+// again after a timeout.
+//
+// Two kinds of backends are supported: the OSC 52 terminal escape sequence,
+// which also works over SSH if the terminal allows it, and local clipboard
+// commands such as wl-copy, xclip, xsel or pbcopy.
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend writes to a clipboard.
+type Backend interface {
+	// Name describes the backend for status messages.
+	Name() string
+	// Write replaces the clipboard contents. An empty text clears the clipboard.
+	Write(text string) error
+}
+
+// OSC52 sets the clipboard of the terminal emulator with an escape sequence.
+type OSC52 struct {
+	W    io.Writer // The terminal, usually /dev/tty
+	Tmux bool      // Wrap the sequence so tmux passes it to the outer terminal
+}
+
+// Name implements Backend.
+func (o *OSC52) Name() string {
+	return "terminal (OSC 52)"
+}
+
+// Write implements Backend. Terminals clear the clipboard for an empty payload.
+func (o *OSC52) Write(text string) error {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if o.Tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	_, err := io.WriteString(o.W, seq)
+	return err
+}
+
+// Command runs a local clipboard program that reads the new contents from stdin.
+type Command struct {
+	Copy  []string // Copy command and arguments
+	Clear []string // Optional command clearing the clipboard, Copy with empty input otherwise
+}
+
+// Name implements Backend.
+func (c *Command) Name() string {
+	return c.Copy[0]
+}
+
+// commandWaitDelay bounds how long Write waits for the output of a clipboard
+// command after it exited.
+const commandWaitDelay = time.Second
+
+// Write implements Backend.
+//
+// Programs like xclip and wl-copy fork a child that keeps serving the
+// clipboard and inherits the standard streams. Stderr therefore goes to a
+// temporary file rather than a pipe, so Write does not wait for that child.
+func (c *Command) Write(text string) error {
+	args := c.Copy
+	if text == "" && len(c.Clear) > 0 {
+		args = c.Clear
+	}
+	stderr, err := os.CreateTemp("", "gophkeeper-clipboard-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = stderr
+	cmd.WaitDelay = commandWaitDelay
+	if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		out, _ := os.ReadFile(stderr.Name())
+		return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// localCommands are tried in order by Detect. env must be set for the command to be used.
+var localCommands = []struct {
+	goos  string
+	env   string
+	copy  []string
+	clear []string
+}{
+	{"", "WAYLAND_DISPLAY", []string{"wl-copy"}, []string{"wl-copy", "--clear"}},
+	{"", "DISPLAY", []string{"xclip", "-selection", "clipboard"}, nil},
+	{"", "DISPLAY", []string{"xsel", "--clipboard", "--input"}, []string{"xsel", "--clipboard", "--delete"}},
+	{"darwin", "", []string{"pbcopy"}, nil},
+}
+
+// Detect returns the backend for mode: "auto" (or empty), "osc52" or a copy
+// command such as "xclip -selection clipboard". In auto mode a local
+// clipboard command is preferred, unless the session runs over SSH where only
+// OSC 52 reaches the user's clipboard. tty receives OSC 52 sequences.
+func Detect(mode string, tty io.Writer) (Backend, error) {
+	osc52 := &OSC52{W: tty, Tmux: os.Getenv("TMUX") != ""}
+
+	switch mode = strings.TrimSpace(mode); mode {
+	case "osc52":
+		return osc52, nil
+	case "", "auto":
+		if os.Getenv("SSH_TTY") != "" {
+			return osc52, nil
+		}
+		for _, c := range localCommands {
+			if (c.goos != "" && c.goos != runtime.GOOS) || (c.env != "" && os.Getenv(c.env) == "") {
+				continue
+			}
+			if _, err := exec.LookPath(c.copy[0]); err == nil {
+				return &Command{Copy: c.copy, Clear: c.clear}, nil
+			}
+		}
+		return osc52, nil
+	default:
+		args := strings.Fields(mode)
+		if _, err := exec.LookPath(args[0]); err != nil {
+			return nil, fmt.Errorf("clipboard command %q: %w", args[0], err)
+		}
+		return &Command{Copy: args}, nil
+	}
+}
+
+// Clipboard copies values and clears them after a timeout.
+type Clipboard struct {
+	backend Backend
+	timeout time.Duration
+
+	// Dispatch runs delayed clears, e.g. on the UI goroutine so OSC 52
+	// sequences do not interleave with screen updates. nil runs them directly.
+	Dispatch func(func())
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+// New creates a clipboard clearing copied values after timeout, or never if timeout is 0.
+func New(backend Backend, timeout time.Duration) *Clipboard {
+	return &Clipboard{backend: backend, timeout: timeout}
+}
+
+// Backend returns the backend in use.
+func (c *Clipboard) Backend() Backend {
+	return c.backend
+}
+
+// Timeout returns the time after which copied values are cleared.
+func (c *Clipboard) Timeout() time.Duration {
+	return c.timeout
+}
+
+// Copy puts text on the clipboard and schedules clearing it. Copying again
+// restarts the timeout.
+func (c *Clipboard) Copy(text string) error {
+	if err := c.backend.Write(text); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.timeout > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(c.timeout, func() {
+			c.mu.Lock()
+			current := c.timer == timer
+			if current {
+				c.timer = nil
+			}
+			c.mu.Unlock()
+			if !current {
+				return
+			}
+			if c.Dispatch != nil {
+				c.Dispatch(func() { _ = c.backend.Write("") })
+			} else {
+				_ = c.backend.Write("")
+			}
+		})
+		c.timer = timer
+	}
+	return nil
+}
+
+// Close clears the clipboard immediately if a copied value is still pending.
+func (c *Clipboard) Close() error {
+	c.mu.Lock()
+	pending := c.timer != nil
+	if pending {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+
+	if !pending {
+		return nil
+	}
+	return c.backend.Write("")
+}
@@ -0,0 +1,130 @@
+package clipboard
+
+import (
+	"bytes"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBackend records clipboard writes
+type fakeBackend struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (f *fakeBackend) Name() string { return "fake" }
+
+func (f *fakeBackend) Write(text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = append(f.writes, text)
+	return nil
+}
+
+func (f *fakeBackend) history() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.writes...)
+}
+
+// TestOSC52 ensures the escape sequence carries the base64 encoded text
+func TestOSC52(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, (&OSC52{W: &buf}).Write("hunter2"))
+	assert.Equal(t, "\x1b]52;c;aHVudGVyMg==\a", buf.String())
+
+	buf.Reset()
+	require.NoError(t, (&OSC52{W: &buf, Tmux: true}).Write(""))
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;\a\x1b\\", buf.String())
+}
+
+// TestAutoClear ensures copied values are cleared after the timeout
+func TestAutoClear(t *testing.T) {
+	backend := &fakeBackend{}
+	clip := New(backend, 50*time.Millisecond)
+
+	require.NoError(t, clip.Copy("secret"))
+	assert.Equal(t, []string{"secret"}, backend.history())
+
+	assert.Eventually(t, func() bool {
+		return len(backend.history()) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"secret", ""}, backend.history())
+
+	assert.NoError(t, clip.Close(), "Nothing should be pending after the clear")
+	assert.Len(t, backend.history(), 2)
+}
+
+// TestCopyRestartsTimeout ensures a new copy is not cleared by an older timer
+func TestCopyRestartsTimeout(t *testing.T) {
+	backend := &fakeBackend{}
+	clip := New(backend, 80*time.Millisecond)
+
+	require.NoError(t, clip.Copy("first"))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, clip.Copy("second"))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{"first", "second"}, backend.history(), "First timer should have been cancelled")
+
+	assert.Eventually(t, func() bool {
+		return len(backend.history()) == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestCloseClearsPending ensures exiting clears a value that is still on the clipboard
+func TestCloseClearsPending(t *testing.T) {
+	backend := &fakeBackend{}
+	clip := New(backend, time.Hour)
+	var dispatched bool
+	clip.Dispatch = func(f func()) { dispatched = true; f() }
+
+	require.NoError(t, clip.Copy("secret"))
+	require.NoError(t, clip.Close())
+	assert.Equal(t, []string{"secret", ""}, backend.history())
+	assert.False(t, dispatched, "Close should clear synchronously")
+}
+
+// TestCommandForkedChild ensures Write returns when the clipboard command
+// leaves a child running that inherited its streams, as xclip does
+func TestCommandForkedChild(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	cmd := &Command{Copy: []string{"sh", "-c", "cat >/dev/null; sleep 5 &"}}
+
+	start := time.Now()
+	require.NoError(t, cmd.Write("secret"))
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+// TestCommandError ensures failures include the command's error output
+func TestCommandError(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	cmd := &Command{Copy: []string{"sh", "-c", "echo no display >&2; exit 1"}}
+
+	err := cmd.Write("secret")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no display")
+}
+
+// TestDetect ensures explicit modes are honoured
+func TestDetect(t *testing.T) {
+	backend, err := Detect("osc52", &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.IsType(t, &OSC52{}, backend)
+
+	t.Setenv("SSH_TTY", "/dev/pts/1")
+	backend, err = Detect("auto", &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.IsType(t, &OSC52{}, backend, "Remote sessions should use the terminal clipboard")
+
+	_, err = Detect("no-such-clipboard-tool --copy", nil)
+	assert.Error(t, err)
+}
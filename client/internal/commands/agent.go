@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/agent"
+	"github.com/golangTroshin/gophkeeper/client/internal/config"
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	"github.com/golangTroshin/gophkeeper/client/internal/socket"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// runAgent keeps the session of the next login in memory until locked, so
+// later commands do not ask for the password again.
+//
+// Example:
+//
+//	gophkeeper agent -timeout 15m &
+//	gophkeeper otp 42   # asks for the password once
+//	gophkeeper otp 57   # reuses the agent's session
+//	gophkeeper lock
+func runAgent(_ pb.GophKeeperServiceClient, args []string) error {
+	timeout := time.Duration(profile.Timeouts.Lock)
+	fs := newFlagSet("agent")
+	socketPath := fs.String("socket", agentSocketPath(), "path of the agent Unix socket")
+	fs.DurationVar(&timeout, "timeout", timeout, "lock after this long without requests, 0 to only lock explicitly")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if locked, _, err := agent.NewClient(*socketPath).Status(); err == nil {
+		return fmt.Errorf("an agent is already running on %s (locked: %t)", *socketPath, locked)
+	}
+
+	a := agent.New(timeout)
+	listener, err := socket.Listen(*socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socketPath)
+	defer a.Lock()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	fmt.Fprintf(output, "Agent listening on %s\n", *socketPath)
+	return a.Serve(listener)
+}
+
+// runLock forgets the saved session and makes the agent forget its session,
+// so the next command asks for the password.
+func runLock(_ pb.GophKeeperServiceClient, args []string) error {
+	fs := newFlagSet("lock")
+	socketPath := fs.String("socket", agentSocketPath(), "path of the agent Unix socket")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	handlers.Lock()
+	err := agent.NewClient(*socketPath).Lock()
+	switch {
+	case errors.Is(err, agent.ErrNotRunning):
+		fmt.Fprintln(output, "Locked")
+	case err != nil:
+		return err
+	default:
+		fmt.Fprintln(output, "Locked, including the agent")
+	}
+	return nil
+}
+
+// agentSocketPath returns the socket set by agent.SocketEnv or the default
+// location. Every profile has its own agent, as sessions belong to a server.
+func agentSocketPath() string {
+	if path := os.Getenv(agent.SocketEnv); path != "" {
+		return path
+	}
+	if profile.Name != config.DefaultProfile {
+		return defaultSocketPath("agent-" + profile.Name + ".sock")
+	}
+	return defaultSocketPath("agent.sock")
+}
+
+// resumeFromAgent restores the session of a running, unlocked agent.
+func resumeFromAgent() bool {
+	s, err := agent.NewClient(agentSocketPath()).Get()
+	if err != nil {
+		return false
+	}
+	defer s.Wipe()
+	handlers.Resume(s.Username, s.Token, s.Key)
+	return true
+}
+
+// shareWithAgent hands the current session to a running agent. Failures are
+// reported but do not fail the command.
+func shareWithAgent(client pb.GophKeeperServiceClient) {
+	c := agent.NewClient(agentSocketPath())
+	if _, _, err := c.Status(); err != nil {
+		return // No agent
+	}
+
+	key, err := handlers.SessionKey(client)
+	if err != nil {
+		fmt.Fprintf(output, "Could not unlock the agent: %v\n", err)
+		return
+	}
+	s := &agent.Session{Username: handlers.Username(), Token: handlers.Token(), Key: key}
+	defer s.Wipe()
+	if err := c.Store(s); err != nil && !errors.Is(err, agent.ErrNotRunning) {
+		fmt.Fprintf(output, "Could not unlock the agent: %v\n", err)
+	}
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/card"
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// runCards prints cards that have expired or expire within the given number of days.
+//
+// Example:
+//
+//	gophkeeper cards -days 30
+func runCards(client pb.GophKeeperServiceClient, args []string) error {
+	fs := newFlagSet("cards")
+	days := fs.Int("days", 60, "report cards expiring within this many days")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *days < 0 {
+		return fmt.Errorf("-days cannot be negative")
+	}
+
+	if err := authenticate(client); err != nil {
+		return err
+	}
+	items, err := handlers.GetItems(client, pb.DataType_CARD)
+	if err != nil {
+		return fmt.Errorf("failed to load items: %w", err)
+	}
+
+	now := time.Now()
+	expiring := card.ExpiringCards(items, now, time.Duration(*days)*24*time.Hour)
+	for _, e := range expiring {
+		fmt.Fprintf(output, "%s: %s\n", e.Metadata, e.String(now))
+	}
+	fmt.Fprintf(output, "%d of %d cards expire within %d days\n", len(expiring), len(items), *days)
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package commands implements the non-interactive command line interface of
+// the GophKeeper client. Every command parses its own flag set and talks to
+// the server through the handlers package, just like the TUI forms do.
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/config"
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/manifoldco/promptui"
+)
+
+// command describes a single CLI subcommand.
+type command struct {
+	usage string
+	run   func(client pb.GophKeeperServiceClient, args []string) error
+}
+
+// registry maps subcommand names to their implementation.
+var registry = map[string]command{
+	"import":    {usage: "import items from another password manager or a GophKeeper archive", run: runImport},
+	"export":    {usage: "export the vault to an encrypted archive or plaintext file", run: runExport},
+	"generate":  {usage: "generate a random password or passphrase", run: runGenerate},
+	"report":    {usage: "list weak, reused and breached passwords", run: runReport},
+	"cards":     {usage: "list expired and soon expiring cards", run: runCards},
+	"otp":       {usage: "print the current code of a one-time password item", run: runOTP},
+	"ssh-agent": {usage: "serve SSH keys from the vault over an ssh-agent socket", run: runSSHAgent},
+	"agent":     {usage: "keep the session unlocked in the background for other commands", run: runAgent},
+	"lock":      {usage: "forget the saved session and lock the running agent", run: runLock},
+}
+
+// output is where commands print their results.
+var output io.Writer = os.Stdout
+
+// profile holds the settings of the selected client profile.
+var profile = config.Defaults()
+
+// Configure applies the settings of a client profile. It is called before Run.
+func Configure(p config.Profile) {
+	profile = p
+}
+
+// Run executes the subcommand named by args[0] with the remaining arguments.
+func Run(client pb.GophKeeperServiceClient, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given")
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage()
+		return nil
+	}
+
+	cmd, ok := registry[args[0]]
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd.run(client, args[1:])
+}
+
+// printUsage lists the available subcommands.
+func printUsage() {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(output, "Usage: gophkeeper [global flags] [command] [flags]")
+	fmt.Fprintln(output, "Without a command the interactive TUI is started.")
+	fmt.Fprintln(output, "Run 'gophkeeper -h' to list the global flags such as -profile.")
+	fmt.Fprintln(output, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(output, "  %-10s %s\n", name, registry[name].usage)
+	}
+}
+
+// newFlagSet creates a flag set for a subcommand that reports errors instead of exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	return fs
+}
+
+// authenticate reuses the session of an unlocked agent or of a previous run,
+// or prompts for credentials and logs the user in. A running agent is
+// unlocked with the session.
+func authenticate(client pb.GophKeeperServiceClient) error {
+	if resumeFromAgent() {
+		return nil
+	}
+	if err := handlers.ResumeSaved(client); err == nil {
+		shareWithAgent(client)
+		return nil
+	}
+
+	username, err := (&promptui.Prompt{Label: "Username", Default: profile.Username}).Run()
+	if err != nil {
+		return err
+	}
+	password, err := promptSecret("Password")
+	if err != nil {
+		return err
+	}
+	if err := handlers.Login(client, username, password); err != nil {
+		return err
+	}
+	shareWithAgent(client)
+	return nil
+}
+
+// register prompts for new account details and signs the user up.
+func register(client pb.GophKeeperServiceClient) error {
+	username, err := (&promptui.Prompt{Label: "New username"}).Run()
+	if err != nil {
+		return err
+	}
+	password, err := promptNewSecret("New password")
+	if err != nil {
+		return err
+	}
+	seed, err := promptNewSecret("Master seed")
+	if err != nil {
+		return err
+	}
+	return handlers.SignUp(client, username, password, seed)
+}
+
+// promptSecret asks for a value without echoing it.
+func promptSecret(label string) (string, error) {
+	return (&promptui.Prompt{Label: label, Mask: '*'}).Run()
+}
+
+// promptNewSecret asks for a non-empty secret twice and makes sure both entries match.
+func promptNewSecret(label string) (string, error) {
+	secret, err := promptSecret(label)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", fmt.Errorf("%s cannot be empty", strings.ToLower(label))
+	}
+	repeated, err := promptSecret("Repeat " + strings.ToLower(label))
+	if err != nil {
+		return "", err
+	}
+	if secret != repeated {
+		return "", fmt.Errorf("entries do not match")
+	}
+	return secret, nil
+}
+
+// confirm asks a yes/no question and reports whether the user agreed.
+func confirm(label string) bool {
+	_, err := (&promptui.Prompt{Label: label, IsConfirm: true}).Run()
+	return err == nil
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/archive"
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// Plaintext export formats.
+const (
+	plainJSON = "json"
+	plainCSV  = "csv"
+)
+
+// runExport writes every vault item to a password protected archive, or to a
+// plaintext JSON/CSV file once the user explicitly confirms it.
+//
+// Examples:
+//
+//	gophkeeper export -file backup.gkarchive
+//	gophkeeper export -file vault.csv -plaintext csv
+func runExport(client pb.GophKeeperServiceClient, args []string) error {
+	fs := newFlagSet("export")
+	file := fs.String("file", "", "path of the file to create")
+	plaintext := fs.String("plaintext", "", "write unencrypted data instead of an archive: json or csv")
+	yes := fs.Bool("yes", false, "do not ask for confirmation before writing plaintext data")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("-file is required")
+	}
+	if *plaintext != "" && *plaintext != plainJSON && *plaintext != plainCSV {
+		return fmt.Errorf("unknown plaintext format %q, use json or csv", *plaintext)
+	}
+
+	if *plaintext != "" && !*yes {
+		fmt.Fprintf(output, "WARNING: %s will contain all of your secrets without any encryption.\n", *file)
+		if !confirm("Write unencrypted export") {
+			return fmt.Errorf("export cancelled")
+		}
+	}
+
+	var password string
+	if *plaintext == "" {
+		var err error
+		if password, err = promptNewSecret("Archive password"); err != nil {
+			return err
+		}
+	}
+
+	if err := authenticate(client); err != nil {
+		return err
+	}
+	items, err := handlers.GetAllItems(client)
+	if err != nil {
+		return fmt.Errorf("failed to load items: %w", err)
+	}
+	vault := newArchiveVault(items)
+
+	f, err := os.OpenFile(*file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch *plaintext {
+	case plainJSON:
+		err = archive.WritePlainJSON(f, vault)
+	case plainCSV:
+		err = archive.WritePlainCSV(f, vault)
+	default:
+		err = archive.Seal(f, vault, password)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(output, "Exported %d items to %s\n", len(vault.Items), *file)
+	return nil
+}
+
+// newArchiveVault converts decrypted vault items into the archive payload.
+func newArchiveVault(items []*pb.DataItem) *archive.Vault {
+	vault := &archive.Vault{ExportedAt: time.Now().UTC()}
+	for _, item := range items {
+		fields := make(map[string]string)
+		if err := json.Unmarshal(item.Data, &fields); err != nil {
+			fields = map[string]string{"data": string(item.Data)}
+		}
+		vault.Items = append(vault.Items, archive.Item{
+			Type:     item.DataType.String(),
+			Metadata: item.Metadata,
+			Fields:   fields,
+		})
+	}
+	return vault
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/generator"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// runGenerate prints random passwords or diceware passphrases. It works
+// offline and does not require a login.
+//
+// Examples:
+//
+//	gophkeeper generate -length 32 -symbols=false
+//	gophkeeper generate -passphrase -words 7 -separator " "
+func runGenerate(_ pb.GophKeeperServiceClient, args []string) error {
+	defaults := generator.DefaultOptions()
+	phraseDefaults := generator.DefaultPassphraseOptions()
+
+	fs := newFlagSet("generate")
+	length := fs.Int("length", defaults.Length, "password length")
+	lower := fs.Bool("lower", defaults.Lower, "include lowercase letters")
+	upper := fs.Bool("upper", defaults.Upper, "include uppercase letters")
+	digits := fs.Bool("digits", defaults.Digits, "include digits")
+	symbols := fs.Bool("symbols", defaults.Symbols, "include symbols")
+	noAmbiguous := fs.Bool("no-ambiguous", defaults.ExcludeAmbiguous, "exclude ambiguous characters such as l, 1, O and 0")
+	passphrase := fs.Bool("passphrase", false, "generate a diceware passphrase instead of a password")
+	wordCount := fs.Int("words", phraseDefaults.Words, "number of passphrase words")
+	separator := fs.String("separator", phraseDefaults.Separator, "passphrase word separator")
+	capitalize := fs.Bool("capitalize", phraseDefaults.Capitalize, "capitalize passphrase words")
+	count := fs.Int("count", 1, "number of values to generate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := generator.Options{
+		Length:           *length,
+		Lower:            *lower,
+		Upper:            *upper,
+		Digits:           *digits,
+		Symbols:          *symbols,
+		ExcludeAmbiguous: *noAmbiguous,
+	}
+	phraseOpts := generator.PassphraseOptions{Words: *wordCount, Separator: *separator, Capitalize: *capitalize}
+
+	for i := 0; i < *count; i++ {
+		var value string
+		var err error
+		if *passphrase {
+			value, err = generator.Passphrase(phraseOpts)
+		} else {
+			value, err = generator.Password(opts)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(output, value)
+	}
+
+	entropy := opts.Entropy()
+	if *passphrase {
+		entropy = phraseOpts.Entropy()
+	}
+	// Keep stdout limited to the generated values so it can be piped.
+	fmt.Fprintf(os.Stderr, "Entropy: %.0f bits\n", entropy)
+	return nil
+}
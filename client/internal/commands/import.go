@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	"github.com/golangTroshin/gophkeeper/client/internal/importer"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// runImport parses an export file, previews the resulting items and stores
+// the ones that are not already in the vault.
+//
+// Examples:
+//
+//	gophkeeper import -format csv -file db.csv -type CREDENTIALS -map metadata=Name,login=User,password=Pass
+//	gophkeeper import -format gophkeeper -file backup.gkarchive -signup
+func runImport(client pb.GophKeeperServiceClient, args []string) error {
+	fs := newFlagSet("import")
+	format := fs.String("format", "", fmt.Sprintf("export format: %s", joinFormats(importer.Formats)))
+	file := fs.String("file", "", "path to the export file")
+	dryRun := fs.Bool("dry-run", false, "show what would be imported without storing anything")
+	dataType := fs.String("type", pb.DataType_CREDENTIALS.String(), "data type for generic CSV rows")
+	mapping := fs.String("map", "", "generic CSV column mapping, e.g. metadata=Name,login=User,password=Pass")
+	signup := fs.Bool("signup", false, "create a new account and import into it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format == "" || *file == "" {
+		fs.Usage()
+		return fmt.Errorf("both -format and -file are required")
+	}
+	if *signup && *dryRun {
+		// A dry run must not create an account, and a new one has nothing to dedupe against.
+		return fmt.Errorf("-signup cannot be combined with -dry-run")
+	}
+
+	opts := importer.Options{}
+	switch importer.Format(*format) {
+	case importer.FormatCSV:
+		value, ok := pb.DataType_value[strings.ToUpper(*dataType)]
+		if !ok {
+			return fmt.Errorf("unknown data type %q", *dataType)
+		}
+		opts.DataType = pb.DataType(value)
+
+		m, err := importer.ParseMapping(*mapping)
+		if err != nil {
+			return err
+		}
+		opts.Mapping = m
+	case importer.FormatArchive:
+		password, err := promptSecret("Archive password")
+		if err != nil {
+			return err
+		}
+		opts.Password = password
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	result, err := importer.Parse(importer.Format(*format), f, opts)
+	if err != nil {
+		return err
+	}
+
+	login := authenticate
+	if *signup {
+		login = register
+	}
+	if err := login(client); err != nil {
+		return err
+	}
+
+	items, err := handlers.GetAllItems(client)
+	if err != nil {
+		return fmt.Errorf("failed to load existing items: %w", err)
+	}
+	existing := make([]importer.Entry, 0, len(items))
+	for _, item := range items {
+		entry, err := importer.EntryFromItem(item)
+		if err != nil {
+			continue // items that are not field maps can never be duplicates
+		}
+		existing = append(existing, entry)
+	}
+
+	unique, duplicates := importer.Dedupe(result.Entries, existing)
+
+	for _, e := range unique {
+		fmt.Fprintf(output, "  new        %s\n", e.Summary())
+	}
+	for _, e := range duplicates {
+		fmt.Fprintf(output, "  duplicate  %s\n", e.Summary())
+	}
+	for _, reason := range result.Skipped {
+		fmt.Fprintf(output, "  skipped    %s\n", reason)
+	}
+	fmt.Fprintf(output, "%d new, %d duplicates, %d skipped\n", len(unique), len(duplicates), len(result.Skipped))
+
+	if *dryRun {
+		fmt.Fprintln(output, "Dry run: nothing was imported.")
+		return nil
+	}
+
+	for i, e := range unique {
+		data := make(map[string]string, len(e.Fields)+1)
+		for name, value := range e.Fields {
+			data[name] = value
+		}
+		data["metadata"] = e.Metadata
+
+		if err := handlers.SaveData(client, nil, e.DataType, data); err != nil {
+			return fmt.Errorf("imported %d of %d items, failed on %q: %w", i, len(unique), e.Metadata, err)
+		}
+	}
+	fmt.Fprintf(output, "Imported %d items.\n", len(unique))
+	return nil
+}
+
+// joinFormats renders the list of supported formats for flag help.
+func joinFormats(formats []importer.Format) string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	"github.com/golangTroshin/gophkeeper/client/internal/otp"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// runOTP prints the current one-time password of an OTP item. Item IDs are
+// shown next to each item in the TUI data list.
+//
+// Example:
+//
+//	gophkeeper otp 42
+func runOTP(client pb.GophKeeperServiceClient, args []string) error {
+	fs := newFlagSet("otp")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: gophkeeper otp <id>")
+	}
+	id, err := strconv.ParseUint(fs.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid item ID %q", fs.Arg(0))
+	}
+
+	if err := authenticate(client); err != nil {
+		return err
+	}
+	item, err := handlers.GetItem(client, id)
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("no OTP item with ID %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load item: %w", err)
+	}
+	if item.DataType != pb.DataType_OTP {
+		return fmt.Errorf("item %d is not an OTP item", id)
+	}
+
+	key, err := otp.ParseItem(item.Data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	code, err := key.Code(now)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(output, code)
+	fmt.Fprintf(output, "%s, valid for %ds\n", key.Label(), int(key.Remaining(now).Seconds()))
+	return nil
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	"github.com/golangTroshin/gophkeeper/client/internal/strength"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// runReport prints weak, reused and breached passwords stored in the vault.
+//
+// Example:
+//
+//	gophkeeper report -hibp ~/hibp/ranges
+func runReport(client pb.GophKeeperServiceClient, args []string) error {
+	fs := newFlagSet("report")
+	hibp := fs.String("hibp", "", "Pwned Passwords range directory or hash file (default $"+strength.PwnedDirEnv+")")
+	all := fs.Bool("all", false, "also list passwords without problems")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var checker *strength.PwnedChecker
+	var err error
+	if *hibp != "" {
+		checker, err = strength.NewPwnedChecker(*hibp)
+	} else {
+		checker, err = strength.NewPwnedCheckerFromEnv()
+	}
+	if err != nil {
+		return err
+	}
+	if checker == nil {
+		fmt.Fprintln(output, "Breach checks skipped: no Pwned Passwords data configured.")
+	}
+
+	if err := authenticate(client); err != nil {
+		return err
+	}
+	items, err := handlers.GetItems(client, pb.DataType_CREDENTIALS)
+	if err != nil {
+		return fmt.Errorf("failed to load items: %w", err)
+	}
+
+	findings, err := strength.Audit(strength.CredentialsFromItems(items), checker)
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, f := range findings {
+		if f.Problem() {
+			problems++
+		}
+		if f.Problem() || *all {
+			fmt.Fprintln(output, f)
+		}
+	}
+	fmt.Fprintf(output, "%d of %d passwords have problems\n", problems, len(findings))
+	return nil
+}
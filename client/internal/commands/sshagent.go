@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	"github.com/golangTroshin/gophkeeper/client/internal/socket"
+	"github.com/golangTroshin/gophkeeper/client/internal/sshagent"
+	"github.com/golangTroshin/gophkeeper/client/internal/sshkey"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// runSSHAgent serves the vault's SSH keys over an ssh-agent socket until
+// interrupted.
+//
+// Example:
+//
+//	gophkeeper ssh-agent -confirm
+//	export SSH_AUTH_SOCK=/run/user/1000/gophkeeper/ssh-agent.sock
+func runSSHAgent(client pb.GophKeeperServiceClient, args []string) error {
+	fs := newFlagSet("ssh-agent")
+	socketPath := fs.String("socket", defaultSocketPath("ssh-agent.sock"), "path of the agent Unix socket")
+	confirmUse := fs.Bool("confirm", false, "ask for confirmation before every signature")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := authenticate(client); err != nil {
+		return err
+	}
+	items, err := handlers.GetItems(client, pb.DataType_SSH_KEY)
+	if err != nil {
+		return fmt.Errorf("failed to load items: %w", err)
+	}
+
+	var keys []*sshkey.Key
+	for _, item := range items {
+		key, err := sshkey.ParseItem(item.Data)
+		if err != nil {
+			fmt.Fprintf(output, "Skipping %q: %v\n", item.Metadata, err)
+			continue
+		}
+		if key.Comment == "" {
+			key.Comment = item.Metadata
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("the vault has no SSH keys")
+	}
+
+	var confirmFunc sshagent.ConfirmFunc
+	if *confirmUse {
+		confirmFunc = func(comment, fingerprint string) bool {
+			return confirm(fmt.Sprintf("Allow signing with %s (%s)", comment, fingerprint))
+		}
+	}
+	a, err := sshagent.New(keys, confirmFunc)
+	if err != nil {
+		return err
+	}
+
+	listener, err := socket.Listen(*socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socketPath)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	fmt.Fprintf(output, "Serving %d keys. Run:\nexport SSH_AUTH_SOCK=%s\n", len(keys), *socketPath)
+	return sshagent.Serve(listener, a)
+}
+
+// defaultSocketPath returns a per-user location for client sockets.
+func defaultSocketPath(name string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("gophkeeper-%d", os.Getuid()))
+	}
+	return filepath.Join(dir, "gophkeeper", name)
+}
@@ -0,0 +1,370 @@
+// Package config loads the client configuration file.
+//
+// The file holds named profiles, each describing a server and the client
+// settings to use with it:
+//
+//	default_profile: work
+//	profiles:
+//	  work:
+//	    server: vault.example.com:50051
+//	    username: alice
+//	    remember_session: true
+//	    tls:
+//	      enabled: true
+//	      ca_file: ~/.config/gophkeeper/work-ca.pem
+//	      cert_file: ~/.config/gophkeeper/laptop.crt
+//	      key_file: ~/.config/gophkeeper/laptop.key
+//	    timeouts:
+//	      request: 10s
+//	      lock: 15m
+//	      clipboard: 20s
+//	    ui:
+//	      clipboard: osc52
+//	      raw_notes: false
+//
+// Settings missing from a profile keep their defaults. Environment variables
+// override the selected profile, and command line flags override both.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables selecting and overriding the profile.
+const (
+	// FileEnv overrides the location of the configuration file.
+	FileEnv = "GOPHKEEPER_CONFIG"
+	// ProfileEnv selects the profile when --profile is not given.
+	ProfileEnv = "GOPHKEEPER_PROFILE"
+	// ServerEnv overrides the server address of the profile.
+	ServerEnv = "GOPHKEEPER_SERVER"
+	// UsernameEnv overrides the username of the profile.
+	UsernameEnv = "GOPHKEEPER_USERNAME"
+	// LockTimeoutEnv overrides the inactivity timeout, e.g. "10m". "0"
+	// disables automatic locking.
+	LockTimeoutEnv = "GOPHKEEPER_LOCK_TIMEOUT"
+	// ClipboardEnv overrides the clipboard backend: "auto", "osc52" or a copy
+	// command such as "xclip -selection clipboard".
+	ClipboardEnv = "GOPHKEEPER_CLIPBOARD"
+	// ClipboardTimeoutEnv overrides the time after which copied values are
+	// cleared, e.g. "45s". "0" disables clearing.
+	ClipboardTimeoutEnv = "GOPHKEEPER_CLIPBOARD_TIMEOUT"
+)
+
+// Defaults used when neither the file nor the environment set a value.
+const (
+	DefaultProfile          = "default"
+	DefaultServer           = "localhost:50051"
+	DefaultRequestTimeout   = 5 * time.Second
+	DefaultLockTimeout      = 5 * time.Minute
+	DefaultClipboardTimeout = 30 * time.Second
+)
+
+// validName matches profile names, which are also used in file names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Duration is a timeout such as "90s" or "15m". Plain numbers are seconds,
+// as in the timeout environment variables.
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	value, err := ParseTimeout(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", node.Line, node.Value)
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// ParseTimeout parses a timeout such as "90s" or "15m". Plain numbers are seconds.
+func ParseTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, err
+		}
+		d = time.Duration(seconds) * time.Second
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("timeout cannot be negative")
+	}
+	return d, nil
+}
+
+// TLS configures the transport security of the connection.
+type TLS struct {
+	Enabled    bool   `yaml:"enabled"`
+	CAFile     string `yaml:"ca_file"`     // Pinned PEM CA certificates, trusted instead of the system roots
+	ServerName string `yaml:"server_name"` // Name to verify instead of the server host
+	CertFile   string `yaml:"cert_file"`   // Client certificate authenticating this device (mutual TLS)
+	KeyFile    string `yaml:"key_file"`    // Private key of the client certificate
+}
+
+// Timeouts configures how long the client waits and keeps secrets.
+type Timeouts struct {
+	Request   Duration `yaml:"request"`   // Single server call
+	Lock      Duration `yaml:"lock"`      // Inactivity before locking, 0 disables
+	Clipboard Duration `yaml:"clipboard"` // Before copied secrets are cleared, 0 disables
+}
+
+// UI holds preferences of the interactive client.
+type UI struct {
+	Clipboard string `yaml:"clipboard"` // "auto", "osc52" or a copy command
+	RawNotes  bool   `yaml:"raw_notes"` // Show the Markdown source of notes first
+}
+
+// Profile is a named server and the settings used with it.
+type Profile struct {
+	Name            string   `yaml:"-"`
+	Server          string   `yaml:"server"`
+	Username        string   `yaml:"username"`         // Suggested when logging in
+	RememberSession bool     `yaml:"remember_session"` // Keep the login on disk between runs until it expires or is locked, off by default
+	TLS             TLS      `yaml:"tls"`
+	Timeouts        Timeouts `yaml:"timeouts"`
+	UI              UI       `yaml:"ui"`
+}
+
+// Defaults returns the profile used without a configuration file.
+func Defaults() Profile {
+	return Profile{
+		Name:   DefaultProfile,
+		Server: DefaultServer,
+		Timeouts: Timeouts{
+			Request:   Duration(DefaultRequestTimeout),
+			Lock:      Duration(DefaultLockTimeout),
+			Clipboard: Duration(DefaultClipboardTimeout),
+		},
+	}
+}
+
+// Validate checks the profile for values the client cannot work with.
+func (p Profile) Validate() error {
+	if strings.TrimSpace(p.Server) == "" {
+		return fmt.Errorf("profile %q: server is required", p.Name)
+	}
+	if p.Timeouts.Request <= 0 {
+		return fmt.Errorf("profile %q: request timeout must be positive", p.Name)
+	}
+	if (p.TLS.CAFile != "" || p.TLS.CertFile != "") && !p.TLS.Enabled {
+		return fmt.Errorf("profile %q: ca_file and cert_file require tls to be enabled", p.Name)
+	}
+	if (p.TLS.CertFile == "") != (p.TLS.KeyFile == "") {
+		return fmt.Errorf("profile %q: cert_file and key_file must be set together", p.Name)
+	}
+	return nil
+}
+
+// File is a parsed configuration file.
+type File struct {
+	DefaultProfile string
+	Profiles       map[string]Profile
+}
+
+// Path returns the location of the configuration file: FileEnv, or
+// gophkeeper/config.yaml in the user's configuration directory
+// ($XDG_CONFIG_HOME or ~/.config on Linux).
+func Path() (string, error) {
+	if path := os.Getenv(FileEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gophkeeper", "config.yaml"), nil
+}
+
+// Load reads a configuration file. A missing file is an empty configuration.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{Profiles: map[string]Profile{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Parse parses the contents of a configuration file.
+func Parse(data []byte) (*File, error) {
+	var raw struct {
+		DefaultProfile string               `yaml:"default_profile"`
+		Profiles       map[string]yaml.Node `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	f := &File{DefaultProfile: raw.DefaultProfile, Profiles: make(map[string]Profile, len(raw.Profiles))}
+	for name, node := range raw.Profiles {
+		if !validName.MatchString(name) {
+			return nil, fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+		}
+		// Decoding into the defaults keeps the settings the profile leaves out.
+		p := Defaults()
+		if err := node.Decode(&p); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+		p.Name = name
+		p.TLS.CAFile = expandHome(p.TLS.CAFile)
+		p.TLS.CertFile = expandHome(p.TLS.CertFile)
+		p.TLS.KeyFile = expandHome(p.TLS.KeyFile)
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		f.Profiles[name] = p
+	}
+	if f.DefaultProfile != "" {
+		if _, ok := f.Profiles[f.DefaultProfile]; !ok {
+			return nil, fmt.Errorf("default profile %q is not defined", f.DefaultProfile)
+		}
+	}
+	return f, nil
+}
+
+// Names returns the sorted profile names.
+func (f *File) Names() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the named profile, or the default profile if name is
+// empty. Without a configuration file the built-in defaults are used.
+func (f *File) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = f.DefaultProfile
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+	if p, ok := f.Profiles[name]; ok {
+		return p, nil
+	}
+	if name == DefaultProfile {
+		return Defaults(), nil
+	}
+	if len(f.Profiles) == 0 {
+		return Profile{}, fmt.Errorf("unknown profile %q: no profiles are configured", name)
+	}
+	return Profile{}, fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(f.Names(), ", "))
+}
+
+// Overrides are settings given on the command line. Empty values keep the
+// profile's settings.
+type Overrides struct {
+	File     string
+	Profile  string
+	Server   string
+	Username string
+	TLS      *bool
+	CAFile   string // Implies TLS
+}
+
+// Resolve loads the configuration file and returns the selected profile with
+// environment variables and then overrides applied.
+func Resolve(o Overrides) (Profile, error) {
+	path := o.File
+	if path == "" {
+		var err error
+		if path, err = Path(); err != nil {
+			return Profile{}, err
+		}
+	}
+	f, err := Load(path)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	name := o.Profile
+	if name == "" {
+		name = os.Getenv(ProfileEnv)
+	}
+	p, err := f.Profile(name)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	if err := p.applyEnv(); err != nil {
+		return Profile{}, err
+	}
+	if o.Server != "" {
+		p.Server = o.Server
+	}
+	if o.Username != "" {
+		p.Username = o.Username
+	}
+	if o.CAFile != "" {
+		p.TLS.CAFile = expandHome(o.CAFile)
+		p.TLS.Enabled = true
+	}
+	if o.TLS != nil {
+		p.TLS.Enabled = *o.TLS
+	}
+	return p, p.Validate()
+}
+
+// applyEnv overrides profile settings with the environment.
+func (p *Profile) applyEnv() error {
+	if value := os.Getenv(ServerEnv); value != "" {
+		p.Server = value
+	}
+	if value := os.Getenv(UsernameEnv); value != "" {
+		p.Username = value
+	}
+	if value := os.Getenv(ClipboardEnv); value != "" {
+		p.UI.Clipboard = value
+	}
+
+	timeouts := []struct {
+		env string
+		d   *Duration
+	}{
+		{LockTimeoutEnv, &p.Timeouts.Lock},
+		{ClipboardTimeoutEnv, &p.Timeouts.Clipboard},
+	}
+	for _, t := range timeouts {
+		value := os.Getenv(t.env)
+		if value == "" {
+			continue
+		}
+		d, err := ParseTimeout(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", t.env, err)
+		}
+		*t.d = Duration(d)
+	}
+	return nil
+}
+
+// expandHome replaces a leading "~/" with the home directory.
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
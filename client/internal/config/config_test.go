@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+default_profile: work
+profiles:
+  work:
+    server: vault.example.com:50051
+    username: alice
+    remember_session: true
+    tls:
+      enabled: true
+      server_name: vault.internal
+    timeouts:
+      lock: 15m
+      clipboard: 0
+    ui:
+      clipboard: osc52
+  local:
+    timeouts:
+      request: 30
+`
+
+// writeConfig writes a configuration file and returns its path
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+// clearEnv unsets the variables Resolve reads
+func clearEnv(t *testing.T) {
+	for _, env := range []string{FileEnv, ProfileEnv, ServerEnv, UsernameEnv, LockTimeoutEnv, ClipboardEnv, ClipboardTimeoutEnv} {
+		t.Setenv(env, "")
+	}
+}
+
+// TestParse ensures profiles keep the defaults of settings they leave out
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(testConfig))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local", "work"}, f.Names())
+
+	work, err := f.Profile("")
+	require.NoError(t, err)
+	assert.Equal(t, "work", work.Name)
+	assert.Equal(t, "vault.example.com:50051", work.Server)
+	assert.True(t, work.TLS.Enabled)
+	assert.Equal(t, "vault.internal", work.TLS.ServerName)
+	assert.Equal(t, Duration(15*time.Minute), work.Timeouts.Lock)
+	assert.Equal(t, Duration(0), work.Timeouts.Clipboard, "Zero should disable clearing")
+	assert.Equal(t, Duration(DefaultRequestTimeout), work.Timeouts.Request)
+	assert.Equal(t, "osc52", work.UI.Clipboard)
+	assert.True(t, work.RememberSession)
+
+	local, err := f.Profile("local")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultServer, local.Server)
+	assert.Equal(t, Duration(30*time.Second), local.Timeouts.Request, "Plain numbers are seconds")
+	assert.Equal(t, Duration(DefaultLockTimeout), local.Timeouts.Lock)
+	assert.False(t, local.RememberSession, "Sessions are only written to disk on request")
+
+	_, err = f.Profile("home")
+	assert.ErrorContains(t, err, "available: local, work")
+}
+
+// TestParseInvalid ensures mistakes in the file are reported
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"default":  "default_profile: home\nprofiles:\n  work: {}\n",
+		"duration": "profiles:\n  work:\n    timeouts:\n      lock: soon\n",
+		"server":   "profiles:\n  work:\n    server: ''\n",
+		"ca":       "profiles:\n  work:\n    tls:\n      ca_file: ca.pem\n",
+		"syntax":   "profiles: [",
+		"name":     "profiles:\n  ../work: {}\n",
+		"key":      "profiles:\n  work:\n    tls:\n      enabled: true\n      cert_file: laptop.crt\n",
+	}
+	for name, contents := range tests {
+		_, err := Parse([]byte(contents))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestResolveDefaults ensures the client works without a configuration file
+func TestResolveDefaults(t *testing.T) {
+	clearEnv(t)
+	p, err := Resolve(Overrides{File: filepath.Join(t.TempDir(), "missing.yaml")})
+	require.NoError(t, err)
+	assert.Equal(t, Defaults(), p)
+
+	_, err = Resolve(Overrides{File: filepath.Join(t.TempDir(), "missing.yaml"), Profile: "work"})
+	assert.ErrorContains(t, err, "no profiles are configured")
+}
+
+// TestResolveOverrides ensures the environment overrides the file and flags override both
+func TestResolveOverrides(t *testing.T) {
+	clearEnv(t)
+	t.Setenv(FileEnv, writeConfig(t, testConfig))
+	t.Setenv(ProfileEnv, "local")
+	t.Setenv(ServerEnv, "env.example.com:50051")
+	t.Setenv(LockTimeoutEnv, "0")
+
+	p, err := Resolve(Overrides{})
+	require.NoError(t, err)
+	assert.Equal(t, "local", p.Name)
+	assert.Equal(t, "env.example.com:50051", p.Server)
+	assert.Equal(t, Duration(0), p.Timeouts.Lock)
+
+	enabled := true
+	p, err = Resolve(Overrides{Profile: "work", Server: "flag.example.com:443", Username: "bob", TLS: &enabled})
+	require.NoError(t, err)
+	assert.Equal(t, "work", p.Name)
+	assert.Equal(t, "flag.example.com:443", p.Server)
+	assert.Equal(t, "bob", p.Username)
+
+	t.Setenv(ClipboardTimeoutEnv, "later")
+	_, err = Resolve(Overrides{})
+	assert.ErrorContains(t, err, ClipboardTimeoutEnv)
+}
+
+// TestParseTimeout ensures durations and plain seconds are accepted
+func TestParseTimeout(t *testing.T) {
+	timeout, err := ParseTimeout("90")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, timeout)
+
+	timeout, err = ParseTimeout("15m")
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, timeout)
+
+	_, err = ParseTimeout("-1m")
+	assert.Error(t, err)
+	_, err = ParseTimeout("soon")
+	assert.Error(t, err)
+}
+
+// TestCredentials ensures TLS is only used when enabled and CA files are checked
+func TestCredentials(t *testing.T) {
+	creds, err := TLS{}.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, "insecure", creds.Info().SecurityProtocol)
+
+	creds, err = TLS{Enabled: true}.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, "tls", creds.Info().SecurityProtocol)
+
+	_, err = TLS{Enabled: true, CAFile: writeConfig(t, "not a certificate")}.Credentials()
+	assert.ErrorContains(t, err, "no PEM certificates")
+}
@@ -0,0 +1,281 @@
+// Package fields implements custom typed item fields and item templates.
+//
+// Custom fields are stored JSON encoded under the "custom_fields" key of an
+// item's data, next to the fixed fields of its data type. Templates describe
+// which custom fields a new item gets; user templates are stored in the vault
+// as TEMPLATE items so they are encrypted and synced like any other data.
+package fields
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// Kind is the type of a custom field.
+type Kind string
+
+// Supported field kinds.
+const (
+	KindText   Kind = "text"
+	KindHidden Kind = "hidden"
+	KindURL    Kind = "url"
+	KindDate   Kind = "date"
+	KindNumber Kind = "number"
+)
+
+// Kinds lists every supported field kind.
+var Kinds = []Kind{KindText, KindHidden, KindURL, KindDate, KindNumber}
+
+// Item data keys used by this package.
+const (
+	DataKey       = "custom_fields" // Encoded custom fields of an item
+	TemplateKey   = "template"      // Name of the template an item was created from
+	DefinitionKey = "definition"    // Encoded template of a TEMPLATE item
+	TagsKey       = "tags"          // Comma separated item tags
+)
+
+// secretKeys are the standard item data keys holding secrets.
+var secretKeys = map[string]bool{
+	"password":    true,
+	"passphrase":  true,
+	"private_key": true,
+	"uri":         true, // OTP URIs contain the shared secret
+	"totp":        true, // TOTP seeds of imported logins
+	"card_number": true,
+	"cvv":         true,
+	"pin":         true,
+}
+
+// IsSecret reports whether a standard item data key holds a secret that must
+// be masked on screen and never be indexed.
+func IsSecret(key string) bool {
+	return secretKeys[key]
+}
+
+// DateLayout is the format of date fields.
+const DateLayout = time.DateOnly
+
+// Spec describes a custom field without its value.
+type Spec struct {
+	Name string `json:"name"`
+	Kind Kind   `json:"kind"`
+}
+
+// Label returns the form label of the field, e.g. "port (number)".
+func (s Spec) Label() string {
+	return fmt.Sprintf("%s (%s)", s.Name, s.Kind)
+}
+
+// Field is a custom field with its value.
+type Field struct {
+	Name  string `json:"name"`
+	Kind  Kind   `json:"kind"`
+	Value string `json:"value"`
+}
+
+// ParseKind validates a field kind name.
+func ParseKind(s string) (Kind, error) {
+	kind := Kind(strings.ToLower(strings.TrimSpace(s)))
+	for _, k := range Kinds {
+		if k == kind {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown field kind %q", s)
+}
+
+// Validate checks that the value matches the field kind. Empty values are allowed.
+func (f Field) Validate() error {
+	if strings.TrimSpace(f.Name) == "" {
+		return fmt.Errorf("field name cannot be empty")
+	}
+	if f.Value == "" {
+		return nil
+	}
+
+	switch f.Kind {
+	case KindText, KindHidden:
+		return nil
+	case KindURL:
+		u, err := url.Parse(f.Value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s: %q is not a valid URL", f.Name, f.Value)
+		}
+	case KindDate:
+		if _, err := time.Parse(DateLayout, f.Value); err != nil {
+			return fmt.Errorf("%s: %q is not a date in YYYY-MM-DD format", f.Name, f.Value)
+		}
+	case KindNumber:
+		if _, err := strconv.ParseFloat(f.Value, 64); err != nil {
+			return fmt.Errorf("%s: %q is not a number", f.Name, f.Value)
+		}
+	default:
+		return fmt.Errorf("%s: unknown field kind %q", f.Name, f.Kind)
+	}
+	return nil
+}
+
+// ParseSpecs parses a field list such as "host:text, port:number, password:hidden".
+// Fields without a kind are text fields.
+func ParseSpecs(s string) ([]Spec, error) {
+	var specs []Spec
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, kindName, ok := strings.Cut(part, ":")
+		kind := KindText
+		if ok {
+			var err error
+			if kind, err = ParseKind(kindName); err != nil {
+				return nil, err
+			}
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("field name cannot be empty in %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate field %q", name)
+		}
+		seen[name] = true
+		specs = append(specs, Spec{Name: name, Kind: kind})
+	}
+	return specs, nil
+}
+
+// Encode validates fields and encodes them for storage under DataKey.
+func Encode(fields []Field) (string, error) {
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if err := f.Validate(); err != nil {
+			return "", err
+		}
+		if seen[f.Name] {
+			return "", fmt.Errorf("duplicate field %q", f.Name)
+		}
+		seen[f.Name] = true
+	}
+	encoded, err := json.Marshal(fields)
+	return string(encoded), err
+}
+
+// FromData decodes the custom fields of decrypted item data. Items without
+// custom fields return an empty slice.
+func FromData(data map[string]string) ([]Field, error) {
+	encoded, ok := data[DataKey]
+	if !ok || encoded == "" {
+		return nil, nil
+	}
+	var fields []Field
+	if err := json.Unmarshal([]byte(encoded), &fields); err != nil {
+		return nil, fmt.Errorf("invalid custom fields: %w", err)
+	}
+	return fields, nil
+}
+
+// ParseTags splits a comma separated tag list into lower case tags without
+// duplicates. A leading '#' is removed from each tag.
+func ParseTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Template describes the custom fields of a kind of item.
+type Template struct {
+	Name     string `json:"name"`
+	BaseType string `json:"base_type"` // Data type whose standard fields are included
+	Fields   []Spec `json:"fields"`
+}
+
+// Builtin are the templates available to every user.
+var Builtin = []Template{
+	{Name: "Database", BaseType: pb.DataType_CREDENTIALS.String(), Fields: []Spec{
+		{Name: "host", Kind: KindText}, {Name: "port", Kind: KindNumber}, {Name: "database", Kind: KindText},
+	}},
+	{Name: "API key", BaseType: pb.DataType_CUSTOM.String(), Fields: []Spec{
+		{Name: "key", Kind: KindHidden}, {Name: "endpoint", Kind: KindURL}, {Name: "expires", Kind: KindDate},
+	}},
+	{Name: "Wi-Fi", BaseType: pb.DataType_CUSTOM.String(), Fields: []Spec{
+		{Name: "ssid", Kind: KindText}, {Name: "password", Kind: KindHidden}, {Name: "security", Kind: KindText},
+	}},
+}
+
+// DataType returns the data type of items created from the template.
+func (t Template) DataType() pb.DataType {
+	return pb.DataType(pb.DataType_value[t.BaseType])
+}
+
+// Validate checks the template name, base type and field specs.
+func (t Template) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("template name cannot be empty")
+	}
+	switch t.BaseType {
+	case pb.DataType_CREDENTIALS.String(), pb.DataType_TEXT.String(), pb.DataType_CUSTOM.String():
+	default:
+		return fmt.Errorf("templates can be based on CREDENTIALS, TEXT or CUSTOM, not %q", t.BaseType)
+	}
+	if len(t.Fields) == 0 {
+		return fmt.Errorf("template %q has no fields", t.Name)
+	}
+	seen := make(map[string]bool)
+	for _, spec := range t.Fields {
+		if err := (Field{Name: spec.Name, Kind: spec.Kind}).Validate(); err != nil {
+			return err
+		}
+		if _, err := ParseKind(string(spec.Kind)); err != nil {
+			return err
+		}
+		if seen[spec.Name] {
+			return fmt.Errorf("duplicate field %q", spec.Name)
+		}
+		seen[spec.Name] = true
+	}
+	return nil
+}
+
+// ItemData returns the data of the TEMPLATE item storing the template.
+func (t Template) ItemData() (map[string]string, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	definition, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{DefinitionKey: string(definition)}, nil
+}
+
+// ParseTemplateItem restores a template from the decrypted data of a TEMPLATE item.
+func ParseTemplateItem(data []byte) (*Template, error) {
+	var item map[string]string
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("invalid template item: %w", err)
+	}
+	var t Template
+	if err := json.Unmarshal([]byte(item[DefinitionKey]), &t); err != nil {
+		return nil, fmt.Errorf("invalid template definition: %w", err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
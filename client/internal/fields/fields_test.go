@@ -0,0 +1,115 @@
+package fields
+
+import (
+	"encoding/json"
+	"testing"
+
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidate ensures values are checked against their kind
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		field Field
+		valid bool
+	}{
+		{Field{Name: "note", Kind: KindText, Value: "anything"}, true},
+		{Field{Name: "key", Kind: KindHidden, Value: "s3cr3t"}, true},
+		{Field{Name: "endpoint", Kind: KindURL, Value: "https://api.example.com/v1"}, true},
+		{Field{Name: "endpoint", Kind: KindURL, Value: "api.example.com"}, false},
+		{Field{Name: "expires", Kind: KindDate, Value: "2027-03-31"}, true},
+		{Field{Name: "expires", Kind: KindDate, Value: "31.03.2027"}, false},
+		{Field{Name: "port", Kind: KindNumber, Value: "5432"}, true},
+		{Field{Name: "port", Kind: KindNumber, Value: "fifty"}, false},
+		{Field{Name: "port", Kind: KindNumber, Value: ""}, true},
+		{Field{Name: "", Kind: KindText, Value: "x"}, false},
+		{Field{Name: "odd", Kind: "color", Value: "red"}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.field.Validate()
+		if tt.valid {
+			assert.NoError(t, err, "%+v", tt.field)
+		} else {
+			assert.Error(t, err, "%+v", tt.field)
+		}
+	}
+}
+
+// TestParseSpecs ensures field lists are parsed with text as the default kind
+func TestParseSpecs(t *testing.T) {
+	specs, err := ParseSpecs("host, port:number , password: HIDDEN,")
+	require.NoError(t, err)
+	assert.Equal(t, []Spec{
+		{Name: "host", Kind: KindText},
+		{Name: "port", Kind: KindNumber},
+		{Name: "password", Kind: KindHidden},
+	}, specs)
+
+	_, err = ParseSpecs("host, host:url")
+	assert.ErrorContains(t, err, "duplicate")
+
+	_, err = ParseSpecs("host:color")
+	assert.ErrorContains(t, err, "unknown field kind")
+
+	_, err = ParseSpecs(":text")
+	assert.Error(t, err)
+}
+
+// TestParseTags ensures tags are normalized
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"work", "finance"}, ParseTags(" Work, #finance,,work "))
+	assert.Empty(t, ParseTags(" , "))
+}
+
+// TestEncodeRoundTrip ensures custom fields survive storage in item data
+func TestEncodeRoundTrip(t *testing.T) {
+	custom := []Field{
+		{Name: "host", Kind: KindText, Value: "db.internal"},
+		{Name: "port", Kind: KindNumber, Value: "5432"},
+	}
+	encoded, err := Encode(custom)
+	require.NoError(t, err)
+
+	restored, err := FromData(map[string]string{"login": "admin", DataKey: encoded})
+	require.NoError(t, err)
+	assert.Equal(t, custom, restored)
+
+	none, err := FromData(map[string]string{"login": "admin"})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	_, err = Encode([]Field{{Name: "port", Kind: KindNumber, Value: "many"}})
+	assert.Error(t, err)
+}
+
+// TestTemplateItem ensures templates are stored and restored as TEMPLATE items
+func TestTemplateItem(t *testing.T) {
+	template := Template{Name: "Server", BaseType: pb.DataType_CREDENTIALS.String(), Fields: []Spec{
+		{Name: "host", Kind: KindText}, {Name: "root password", Kind: KindHidden},
+	}}
+	data, err := template.ItemData()
+	require.NoError(t, err)
+
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	restored, err := ParseTemplateItem(raw)
+	require.NoError(t, err)
+	assert.Equal(t, template, *restored)
+	assert.Equal(t, pb.DataType_CREDENTIALS, restored.DataType())
+
+	_, err = Template{Name: "Card", BaseType: pb.DataType_CARD.String(), Fields: template.Fields}.ItemData()
+	assert.Error(t, err, "Only some data types can be extended")
+
+	_, err = Template{Name: "Empty", BaseType: pb.DataType_CUSTOM.String()}.ItemData()
+	assert.Error(t, err)
+}
+
+// TestBuiltinTemplates ensures the built-in templates are valid
+func TestBuiltinTemplates(t *testing.T) {
+	for _, template := range Builtin {
+		assert.NoError(t, template.Validate(), template.Name)
+	}
+}
@@ -5,11 +5,28 @@ package forms
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gdamore/tcell/v2"
+	"github.com/golangTroshin/gophkeeper/client/internal/autolock"
+	"github.com/golangTroshin/gophkeeper/client/internal/card"
+	"github.com/golangTroshin/gophkeeper/client/internal/clipboard"
+	"github.com/golangTroshin/gophkeeper/client/internal/config"
+	"github.com/golangTroshin/gophkeeper/client/internal/fields"
+	"github.com/golangTroshin/gophkeeper/client/internal/generator"
 	"github.com/golangTroshin/gophkeeper/client/internal/handlers"
+	"github.com/golangTroshin/gophkeeper/client/internal/markdown"
+	"github.com/golangTroshin/gophkeeper/client/internal/otp"
+	"github.com/golangTroshin/gophkeeper/client/internal/search"
+	"github.com/golangTroshin/gophkeeper/client/internal/sshkey"
+	"github.com/golangTroshin/gophkeeper/client/internal/strength"
 	pb "github.com/golangTroshin/gophkeeper/grpc"
 	"github.com/manifoldco/promptui"
 	"github.com/rivo/tview"
@@ -17,26 +34,151 @@ import (
 
 var lastForm *tview.Form
 
+// profile holds the settings of the selected client profile.
+var profile = config.Defaults()
+
+// Configure applies the settings of a client profile. It is called before
+// ShowVersionInfo.
+func Configure(p config.Profile) {
+	profile = p
+}
+
+// searchIndex holds the decrypted items of the logged in user for searching.
+var searchIndex struct {
+	sync.Mutex
+	index      *search.Index
+	err        error  // Error of the last index build
+	generation uint64 // Incremented by every refresh and wipe, see refreshSearchIndex
+}
+
 // ShowVersionInfo displays the version and build date in a TUI modal
 func ShowVersionInfo(app *tview.Application, client pb.GophKeeperServiceClient, version, buildDate string) {
-	versionText := fmt.Sprintf("GophKeeper CLI\n\nVersion: %s\nBuild Date: %s", version, buildDate)
+	versionText := fmt.Sprintf("GophKeeper CLI\n\nVersion: %s\nBuild Date: %s\nProfile: %s (%s)", version, buildDate, profile.Name, profile.Server)
+
+	setupAutoLock(app, client, time.Duration(profile.Timeouts.Lock))
 
 	modal := tview.NewModal().
 		SetText(versionText).
 		AddButtons([]string{"OK"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-			authentication(app, client) // Proceed to login/signup after closing
+			resumeSession(app, client) // Proceed to login/signup after closing
 		})
 
 	modal.SetBorder(true).SetTitle("Version Info").SetTitleAlign(tview.AlignCenter)
 	app.SetRoot(modal, true).SetFocus(modal)
 }
 
-// Authentication displays the login/signup form to authenticate the user.
+// locker locks the vault after inactivity while a user is logged in.
+var locker = autolock.New(0, func() {})
+
+// screenCleanup stops background updates of the current screen, see leaveScreen.
+var screenCleanup func()
+
+// leaveScreen stops background updates of the current screen, such as OTP countdowns.
+func leaveScreen() {
+	if screenCleanup != nil {
+		screenCleanup()
+		screenCleanup = nil
+	}
+}
+
+// setupAutoLock locks the vault after timeout without key presses or mouse
+// events. Ctrl-L locks immediately.
+func setupAutoLock(app *tview.Application, client pb.GophKeeperServiceClient, timeout time.Duration) {
+	locker = autolock.New(timeout, func() {
+		app.QueueUpdateDraw(func() { lockVault(app, client) })
+	})
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		locker.Touch()
+		if event.Key() == tcell.KeyCtrlL && locker.Active() {
+			locker.Lock()
+			return nil
+		}
+		return event
+	})
+	app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+		locker.Touch()
+		return event, action
+	})
+}
+
+// unlocked starts a session after login, sign-up or unlocking.
+func unlocked(app *tview.Application, client pb.GophKeeperServiceClient) {
+	refreshSearchIndex(app, client)
+	locker.Start()
+	actionTypeSelection(app, client)
+}
+
+// wipeSession drops decrypted data held by the TUI.
+func wipeSession() {
+	leaveScreen()
+	searchIndex.Lock()
+	searchIndex.generation++
+	searchIndex.index, searchIndex.err = nil, nil
+	searchIndex.Unlock()
+	_ = Close()
+	lastForm = nil
+}
+
+// lockVault wipes decrypted data and the session token and shows the lock screen.
+func lockVault(app *tview.Application, client pb.GophKeeperServiceClient) {
+	wipeSession()
+	handlers.Lock()
+	lockScreen(app, client)
+}
+
+// lockScreen asks for the password of the locked user to unlock the vault.
+func lockScreen(app *tview.Application, client pb.GophKeeperServiceClient) {
+	form := tview.NewForm()
+	form.AddTextView("", fmt.Sprintf("The vault of %s is locked.", handlers.Username()), 60, 1, false, false)
+	form.AddPasswordField("Password", "", 20, '*', nil)
+	form.AddButton("Unlock", func() {
+		password := form.GetFormItemByLabel("Password").(*tview.InputField).GetText()
+		if password == "" {
+			errorModal(app, "Password cannot be empty")
+			return
+		}
+		if err := handlers.Login(client, handlers.Username(), password); err != nil {
+			form.GetFormItemByLabel("Password").(*tview.InputField).SetText("")
+			errorModal(app, err.Error())
+			return
+		}
+		unlocked(app, client)
+	})
+	form.AddButton("Logout", func() { authentication(app, client) })
+
+	form.SetBorder(true).SetTitle("Locked").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(form, true).SetFocus(form)
+	lastForm = form
+}
+
+// resumeSession continues the session saved by the previous run, or shows
+// the login form.
+func resumeSession(app *tview.Application, client pb.GophKeeperServiceClient) {
+	if err := handlers.ResumeSaved(client); err == nil {
+		unlocked(app, client)
+		return
+	}
+	loginForm(app, client)
+}
+
+// Authentication logs the user out and displays the login/signup form.
 func authentication(app *tview.Application, client pb.GophKeeperServiceClient) {
+	locker.Stop()
+	wipeSession()
+	handlers.Logout()
+	loginForm(app, client)
+}
+
+// loginForm displays the login/signup form to authenticate the user.
+func loginForm(app *tview.Application, client pb.GophKeeperServiceClient) {
 	form := tview.NewForm()
-	form.AddInputField("Username", "", 20, nil, nil)
+	form.AddInputField("Username", profile.Username, 20, nil, nil)
 	form.AddPasswordField("Password", "", 20, '*', nil)
+	if profile.Username != "" {
+		form.SetFocus(1)
+	}
 	form.AddButton("Login", func() {
 		username := form.GetFormItemByLabel("Username").(*tview.InputField).GetText()
 		password := form.GetFormItemByLabel("Password").(*tview.InputField).GetText()
@@ -54,7 +196,7 @@ func authentication(app *tview.Application, client pb.GophKeeperServiceClient) {
 			errorModal(app, err.Error())
 			return
 		}
-		actionTypeSelection(app, client)
+		unlocked(app, client)
 	})
 	form.AddButton("Sign Up", func() {
 		username := form.GetFormItemByLabel("Username").(*tview.InputField).GetText()
@@ -82,7 +224,7 @@ func authentication(app *tview.Application, client pb.GophKeeperServiceClient) {
 
 // setupMasterSeed prompts the user to enter a master seed for encrypting stored data.
 func setupMasterSeed(app *tview.Application, client pb.GophKeeperServiceClient, username, password string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), handlers.RequestTimeout)
 	defer cancel()
 
 	// Check if user already exists
@@ -113,7 +255,7 @@ func setupMasterSeed(app *tview.Application, client pb.GophKeeperServiceClient,
 			return
 		}
 
-		actionTypeSelection(app, client)
+		unlocked(app, client)
 	})
 
 	form.AddButton("Back", func() {
@@ -135,6 +277,11 @@ func actionTypeSelection(app *tview.Application, client pb.GophKeeperServiceClie
 	form := tview.NewForm()
 	form.AddButton("Save new data", func() { dataTypeSelection(app, client, actions["save"]) })
 	form.AddButton("Get your data", func() { dataTypeSelection(app, client, actions["get"]) })
+	form.AddButton("Search", func() { searchItems(app, client, "") })
+	form.AddButton("Password report", func() { passwordReport(app, client) })
+	form.AddButton("Expiring cards", func() { expiringCards(app, client) })
+	form.AddButton("Usage", func() { showUsage(app, client) })
+	form.AddButton("Lock", func() { locker.Lock() })
 	form.AddButton("Logout", func() { authentication(app, client) })
 
 	form.SetBorder(true).SetTitle("What do you want to do?").SetTitleAlign(tview.AlignLeft)
@@ -149,6 +296,9 @@ func dataTypeSelection(app *tview.Application, client pb.GophKeeperServiceClient
 	form.AddButton("Text Data", func() { handleDataAction(app, client, pb.DataType_TEXT, actionType) })
 	form.AddButton("Binary Data", func() { handleDataAction(app, client, pb.DataType_BINARY, actionType) })
 	form.AddButton("Card Data", func() { handleDataAction(app, client, pb.DataType_CARD, actionType) })
+	form.AddButton("One-Time Password", func() { handleDataAction(app, client, pb.DataType_OTP, actionType) })
+	form.AddButton("SSH Key", func() { handleDataAction(app, client, pb.DataType_SSH_KEY, actionType) })
+	form.AddButton("Custom Item", func() { handleDataAction(app, client, pb.DataType_CUSTOM, actionType) })
 	form.AddButton("Back", func() { actionTypeSelection(app, client) })
 	form.AddButton("Logout", func() { authentication(app, client) })
 
@@ -161,22 +311,162 @@ func dataTypeSelection(app *tview.Application, client pb.GophKeeperServiceClient
 func handleDataAction(app *tview.Application, client pb.GophKeeperServiceClient, dataType pb.DataType, actionType uint) {
 	switch actionType {
 	case actions["save"]:
-		saveData(app, client, dataType, actionType)
+		if dataType == pb.DataType_CUSTOM {
+			templateSelection(app, client, actionType)
+			return
+		}
+		saveData(app, client, dataType, actionType, nil)
 	case actions["get"]:
 		getData(app, client, dataType, actionType)
 	}
 }
 
-// saveData provides a form to save data of the specified type.
-func saveData(app *tview.Application, client pb.GophKeeperServiceClient, dataType pb.DataType, actionType uint) {
+// noteEditor edits the "Text" area of form in a full-screen editor.
+// Esc copies the text back and returns to the form.
+func noteEditor(app *tview.Application, form *tview.Form) {
+	field := form.GetFormItemByLabel("Text").(*tview.TextArea)
+
+	editor := tview.NewTextArea().SetText(field.GetText(), true)
+	editor.SetBorder(true).SetTitle("Note (Markdown) - Esc to return").SetTitleAlign(tview.AlignLeft)
+	editor.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			field.SetText(editor.GetText(), false)
+			app.SetRoot(form, true).SetFocus(field)
+			return nil
+		}
+		return event
+	})
+
+	app.SetRoot(editor, true).SetFocus(editor)
+}
+
+// templateSelection lets the user pick the template of a new custom item.
+func templateSelection(app *tview.Application, client pb.GophKeeperServiceClient, actionType uint) {
+	templates, err := handlers.GetTemplates(client)
+	if err != nil {
+		errorModal(app, fmt.Sprintf("Failed to retrieve templates: %v", err))
+		return
+	}
+
+	list := tview.NewList()
+	list.AddItem("Blank", "Custom item without predefined fields", 0, func() {
+		saveData(app, client, pb.DataType_CUSTOM, actionType, nil)
+	})
+	for _, t := range templates {
+		template := t
+		names := make([]string, 0, len(template.Fields))
+		for _, spec := range template.Fields {
+			names = append(names, spec.Name)
+		}
+		list.AddItem(template.Name, fmt.Sprintf("%s: %s", template.BaseType, strings.Join(names, ", ")), 0, func() {
+			saveData(app, client, template.DataType(), actionType, &template)
+		})
+	}
+	list.AddItem("New template", "Define a reusable set of fields", 'n', func() {
+		newTemplate(app, client, actionType)
+	})
+	list.AddItem("Back", "Return to data types", 'b', func() {
+		dataTypeSelection(app, client, actionType)
+	})
+
+	list.SetBorder(true).SetTitle("Select Template").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(list, true).SetFocus(list)
+}
+
+// newTemplate provides a form to define a template and store it in the vault.
+func newTemplate(app *tview.Application, client pb.GophKeeperServiceClient, actionType uint) {
+	baseTypes := []string{pb.DataType_CUSTOM.String(), pb.DataType_CREDENTIALS.String(), pb.DataType_TEXT.String()}
+
+	form := tview.NewForm()
+	form.AddInputField("Name", "", 40, nil, nil)
+	form.AddDropDown("Base Type", baseTypes, 0, nil)
+	form.AddInputField("Fields", "", 100, nil, nil)
+	form.AddTextView("", "Comma separated name:kind pairs, kinds: text, hidden, url, date, number.", 80, 1, false, false)
+
+	form.AddButton("Save", func() {
+		name := form.GetFormItemByLabel("Name").(*tview.InputField).GetText()
+		_, baseType := form.GetFormItemByLabel("Base Type").(*tview.DropDown).GetCurrentOption()
+		specs, err := fields.ParseSpecs(form.GetFormItemByLabel("Fields").(*tview.InputField).GetText())
+		if err != nil {
+			errorModal(app, fmt.Sprintf("Invalid fields: %v", err))
+			return
+		}
+
+		template := fields.Template{Name: name, BaseType: baseType, Fields: specs}
+		data, err := template.ItemData()
+		if err != nil {
+			errorModal(app, fmt.Sprintf("Invalid template: %v", err))
+			return
+		}
+		data["metadata"] = name
+
+		if err := handlers.SaveData(client, app, pb.DataType_TEMPLATE, data); err != nil {
+			errorModal(app, fmt.Sprintf("Failed to save template: %v", err))
+			return
+		}
+		templateSelection(app, client, actionType)
+	})
+	form.AddButton("Back", func() { templateSelection(app, client, actionType) })
+
+	form.SetBorder(true).SetTitle("New Template").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(form, true).SetFocus(form)
+	lastForm = form
+}
+
+// addField asks for the name and kind of a custom field and passes it to onAdd.
+func addField(app *tview.Application, parent *tview.Form, onAdd func(fields.Spec) error) {
+	kinds := make([]string, len(fields.Kinds))
+	for i, k := range fields.Kinds {
+		kinds[i] = string(k)
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("Field Name", "", 40, nil, nil)
+	form.AddDropDown("Kind", kinds, 0, nil)
+	form.AddButton("Add", func() {
+		name := strings.TrimSpace(form.GetFormItemByLabel("Field Name").(*tview.InputField).GetText())
+		_, kind := form.GetFormItemByLabel("Kind").(*tview.DropDown).GetCurrentOption()
+		if err := onAdd(fields.Spec{Name: name, Kind: fields.Kind(kind)}); err != nil {
+			errorModal(app, err.Error())
+			return
+		}
+		lastForm = parent
+		app.SetRoot(parent, true).SetFocus(parent)
+	})
+	form.AddButton("Cancel", func() {
+		lastForm = parent
+		app.SetRoot(parent, true).SetFocus(parent)
+	})
+
+	form.SetBorder(true).SetTitle("Add Field").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(form, true).SetFocus(form)
+	lastForm = form
+}
+
+// saveData provides a form to save data of the specified type. Items created
+// from a template get its custom fields; more can be added with "Add Field".
+func saveData(app *tview.Application, client pb.GophKeeperServiceClient, dataType pb.DataType, actionType uint, template *fields.Template) {
 	form := tview.NewForm()
 
 	switch dataType {
 	case pb.DataType_CREDENTIALS:
 		form.AddInputField("Login", "", 20, nil, nil)
-		form.AddPasswordField("Password", "", 20, '*', nil)
+		form.AddPasswordField("Password", "", 20, '*', func(text string) {
+			form.GetFormItemByLabel("Strength").(*tview.TextView).SetText(strengthLabel(strength.Check(text)))
+		})
+		form.AddTextView("Strength", "", 40, 1, true, false)
+		form.AddButton("Generate", func() {
+			password, err := generator.Password(generator.DefaultOptions())
+			if err != nil {
+				errorModal(app, fmt.Sprintf("Failed to generate password: %v", err))
+				return
+			}
+			form.GetFormItemByLabel("Password").(*tview.InputField).SetText(password)
+		})
 	case pb.DataType_TEXT:
-		form.AddInputField("Text", "", 100, nil, nil)
+		form.AddTextArea("Text", "", 0, 10, 0, nil)
+		form.GetFormItemByLabel("Text").(*tview.TextArea).SetPlaceholder("Markdown is supported.")
+		form.AddButton("Full Screen", func() { noteEditor(app, form) })
 	case pb.DataType_BINARY:
 		selectedFilePath, err := promptForFilePath()
 		if err != nil {
@@ -185,16 +475,71 @@ func saveData(app *tview.Application, client pb.GophKeeperServiceClient, dataTyp
 		}
 		form.AddInputField("Selected File", selectedFilePath, 100, nil, nil)
 	case pb.DataType_CARD:
-		form.AddInputField("Card Number", "", 20, nil, nil)
-		form.AddInputField("Expiration Date", "", 10, nil, nil)
-		form.AddInputField("CVV", "", 3, nil, nil)
+		form.AddInputField("Card Number", "", 23, nil, func(text string) {
+			form.GetFormItemByLabel("Brand").(*tview.TextView).SetText(string(card.DetectBrand(text)))
+		})
+		form.AddTextView("Brand", "", 20, 1, true, false)
+		form.AddInputField("Expiration Date", "", 7, nil, nil).
+			GetFormItemByLabel("Expiration Date").(*tview.InputField).SetPlaceholder("MM/YY")
+		form.AddPasswordField("CVV", "", 4, '*', nil)
+		form.AddInputField("Cardholder", "", 40, nil, nil)
+		form.AddPasswordField("PIN", "", 12, '*', nil)
+		form.AddInputField("Billing Address", "", 100, nil, nil)
+	case pb.DataType_OTP:
+		form.AddInputField("OTP URI", "", 100, nil, nil)
+	case pb.DataType_SSH_KEY:
+		form.AddInputField("Comment", "", 40, nil, nil)
+		form.AddInputField("Private Key File", "", 100, nil, nil)
+		form.AddPasswordField("Key Passphrase", "", 40, '*', nil)
+		form.AddTextView("", "Leave the key file empty to generate a new Ed25519 key.", 60, 1, false, false)
+	}
+
+	var custom []fields.Spec
+	addCustomField := func(spec fields.Spec) error {
+		if spec.Name == "" {
+			return fmt.Errorf("field name cannot be empty")
+		}
+		for _, existing := range custom {
+			if existing.Name == spec.Name {
+				return fmt.Errorf("duplicate field %q", spec.Name)
+			}
+		}
+		if spec.Kind == fields.KindHidden {
+			form.AddPasswordField(spec.Label(), "", 40, '*', nil)
+		} else {
+			form.AddInputField(spec.Label(), "", 40, nil, nil)
+		}
+		custom = append(custom, spec)
+		return nil
+	}
+	if template != nil {
+		for _, spec := range template.Fields {
+			_ = addCustomField(spec)
+		}
 	}
 
 	form.AddInputField("Description", "", 100, nil, nil)
+	form.AddInputField("Tags", "", 40, nil, nil).
+		GetFormItemByLabel("Tags").(*tview.InputField).SetPlaceholder("work, finance")
 
 	form.AddButton("Save", func() {
 		data := handlers.CollectFormData(form, dataType)
 
+		if len(custom) > 0 {
+			encoded, err := fields.Encode(handlers.CollectCustomFields(form, custom))
+			if err != nil {
+				errorModal(app, fmt.Sprintf("Invalid field: %v", err))
+				return
+			}
+			data[fields.DataKey] = encoded
+			if template != nil {
+				data[fields.TemplateKey] = template.Name
+			}
+		} else if dataType == pb.DataType_CUSTOM {
+			errorModal(app, "Add at least one field to a custom item")
+			return
+		}
+
 		if dataType == pb.DataType_BINARY {
 			fileBytes, err := readBinaryFile(data["file_path"])
 			if err != nil {
@@ -204,14 +549,66 @@ func saveData(app *tview.Application, client pb.GophKeeperServiceClient, dataTyp
 			data["file_data"] = string(fileBytes)
 		}
 
-		err := handlers.SaveData(client, app, dataType, data)
-		if err != nil {
-			errorModal(app, fmt.Sprintf("Failed to save data: %v", err))
+		var warning string
+		if dataType == pb.DataType_CARD {
+			c, err := card.FromFields(data)
+			if err != nil {
+				errorModal(app, fmt.Sprintf("Invalid card: %v", err))
+				return
+			}
+			for k, v := range c.Fields() {
+				data[k] = v
+			}
+			if c.Expiry.Expired(time.Now()) {
+				warning = fmt.Sprintf("This card expired at the end of %s.\n\nSave anyway?", c.Expiry)
+			}
+		}
+
+		if dataType == pb.DataType_OTP {
+			if _, err := otp.Parse(data["uri"]); err != nil {
+				errorModal(app, fmt.Sprintf("Invalid OTP URI: %v", err))
+				return
+			}
+		}
+
+		if dataType == pb.DataType_SSH_KEY {
+			key, err := buildSSHKey(data["key_file"], data["passphrase"], data["comment"])
+			if err != nil {
+				errorModal(app, fmt.Sprintf("Failed to prepare SSH key: %v", err))
+				return
+			}
+			keyData := key.Fields()
+			keyData["metadata"] = data["metadata"]
+			keyData[fields.TagsKey] = data[fields.TagsKey]
+			data = keyData
+		}
+
+		save := func() {
+			err := handlers.SaveData(client, app, dataType, data)
+			if err != nil {
+				errorModal(app, fmt.Sprintf("Failed to save data: %v", err))
+				return
+			}
+			refreshSearchIndex(app, client)
+			dataTypeSelection(app, client, actionType)
+		}
+
+		if dataType == pb.DataType_CREDENTIALS {
+			if warnings := passwordWarnings(data["password"], data["login"], data["metadata"]); len(warnings) > 0 {
+				confirmModal(app, form, "This password has problems:\n\n"+strings.Join(warnings, "\n")+"\n\nSave anyway?", save)
+				return
+			}
+		}
+		if warning != "" {
+			confirmModal(app, form, warning, save)
 			return
 		}
-		dataTypeSelection(app, client, actionType)
+		save()
 	})
 
+	if dataType != pb.DataType_OTP && dataType != pb.DataType_SSH_KEY {
+		form.AddButton("Add Field", func() { addField(app, form, addCustomField) })
+	}
 	form.AddButton("Back", func() { dataTypeSelection(app, client, actionType) })
 
 	form.SetBorder(true).SetTitle("Save Data").SetTitleAlign(tview.AlignLeft)
@@ -219,9 +616,214 @@ func saveData(app *tview.Application, client pb.GophKeeperServiceClient, dataTyp
 	lastForm = form
 }
 
-// getData retrieves stored data and displays it in a list.
+// strengthLabel renders a password estimate as a colored one-line label.
+func strengthLabel(estimate strength.Estimate) string {
+	colors := []string{"red", "red", "orange", "yellow", "green"}
+	names := []string{"very weak", "weak", "fair", "strong", "very strong"}
+	return fmt.Sprintf("[%s]%s[-] (%d/%d)", colors[estimate.Score], names[estimate.Score], estimate.Score, strength.MaxScore)
+}
+
+// passwordWarnings checks a password before it is saved and returns the
+// reasons it should not be used. Breach checks run only if local Pwned
+// Passwords data is configured.
+func passwordWarnings(password string, userInputs ...string) []string {
+	var warnings []string
+
+	estimate := strength.Check(password, userInputs...)
+	if estimate.Weak() {
+		warnings = append(warnings, fmt.Sprintf("Weak password, could be cracked in %s", estimate.CrackTime))
+		for _, w := range estimate.Warnings {
+			warnings = append(warnings, "- "+w)
+		}
+	}
+
+	checker, err := strength.NewPwnedCheckerFromEnv()
+	if err != nil {
+		return append(warnings, fmt.Sprintf("Breach check unavailable: %v", err))
+	}
+	if checker != nil {
+		count, err := checker.Count(password)
+		switch {
+		case err != nil:
+			warnings = append(warnings, fmt.Sprintf("Breach check unavailable: %v", err))
+		case count > 0:
+			warnings = append(warnings, fmt.Sprintf("Password appeared %d times in known data breaches", count))
+		}
+	}
+	return warnings
+}
+
+// passwordReport shows weak, reused and breached passwords across the vault.
+func passwordReport(app *tview.Application, client pb.GophKeeperServiceClient) {
+	items, err := handlers.GetItems(client, pb.DataType_CREDENTIALS)
+	if err != nil {
+		errorModal(app, fmt.Sprintf("Failed to retrieve data: %v", err))
+		return
+	}
+
+	checker, err := strength.NewPwnedCheckerFromEnv()
+	if err != nil {
+		errorModal(app, fmt.Sprintf("Breach check unavailable: %v", err))
+		return
+	}
+
+	findings, err := strength.Audit(strength.CredentialsFromItems(items), checker)
+	if err != nil {
+		errorModal(app, fmt.Sprintf("Failed to check passwords: %v", err))
+		return
+	}
+
+	list := tview.NewList()
+	for _, f := range findings {
+		if f.Problem() {
+			list.AddItem(f.Metadata, f.String(), 0, nil)
+		}
+	}
+	if list.GetItemCount() == 0 {
+		list.AddItem("No problems found", fmt.Sprintf("%d passwords checked", len(findings)), 0, nil)
+	}
+
+	list.AddItem("Back", "Return to main menu", 'b', func() {
+		actionTypeSelection(app, client)
+	})
+
+	list.SetBorder(true).SetTitle("Password Report").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(list, true).SetFocus(list)
+}
+
+// expiringCards lists cards that have expired or expire within two months.
+func expiringCards(app *tview.Application, client pb.GophKeeperServiceClient) {
+	items, err := handlers.GetItems(client, pb.DataType_CARD)
+	if err != nil {
+		errorModal(app, fmt.Sprintf("Failed to retrieve data: %v", err))
+		return
+	}
+
+	now := time.Now()
+	list := tview.NewList()
+	for _, e := range card.ExpiringCards(items, now, 60*24*time.Hour) {
+		list.AddItem(e.Metadata, e.String(now), 0, nil)
+	}
+	if list.GetItemCount() == 0 {
+		list.AddItem("No cards expire within 60 days", fmt.Sprintf("%d cards checked", len(items)), 0, nil)
+	}
+
+	list.AddItem("Back", "Return to main menu", 'b', func() {
+		actionTypeSelection(app, client)
+	})
+
+	list.SetBorder(true).SetTitle("Expiring Cards").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(list, true).SetFocus(list)
+}
+
+// setSearchIndex replaces the search index.
+func setSearchIndex(index *search.Index, err error) {
+	searchIndex.Lock()
+	defer searchIndex.Unlock()
+	searchIndex.index, searchIndex.err = index, err
+}
+
+// refreshSearchIndex rebuilds the search index in the background after login
+// and after items are saved. The fetch uses the session token taken now and
+// runs entirely off the UI goroutine. Its result is installed on the UI
+// goroutine and dropped if the session was wiped or another refresh started
+// meanwhile, so a lock or logout never gets the index back.
+func refreshSearchIndex(app *tview.Application, client pb.GophKeeperServiceClient) {
+	searchIndex.Lock()
+	searchIndex.generation++
+	generation := searchIndex.generation
+	searchIndex.Unlock()
+
+	token := handlers.Token()
+	go func() {
+		var items []*pb.DataItem
+		key, err := handlers.TokenKey(client, token)
+		if err == nil {
+			items, err = handlers.GetAllItemsWith(client, token, key)
+			clear(key) // Wipe the key
+		}
+
+		app.QueueUpdateDraw(func() {
+			searchIndex.Lock()
+			defer searchIndex.Unlock()
+			if searchIndex.generation == generation {
+				searchIndex.index, searchIndex.err = search.New(items), err
+			}
+		})
+	}()
+}
+
+// searchItems shows a search box with results updated while typing.
+// Selecting a result opens its details.
+func searchItems(app *tview.Application, client pb.GophKeeperServiceClient, query string) {
+	searchIndex.Lock()
+	index, indexErr := searchIndex.index, searchIndex.err
+	searchIndex.Unlock()
+	if index == nil {
+		items, err := handlers.GetAllItems(client)
+		if err != nil {
+			errorModal(app, fmt.Sprintf("Failed to retrieve data: %v", err))
+			return
+		}
+		index = search.New(items)
+		setSearchIndex(index, nil)
+	}
+
+	input := tview.NewInputField().
+		SetLabel("Search: ").
+		SetPlaceholder("words, type:card, #tag").
+		SetText(query)
+	status := tview.NewTextView().SetDynamicColors(true)
+	list := tview.NewList()
+
+	update := func(query string) {
+		list.Clear()
+		results := index.Search(query, 100)
+		for _, r := range results {
+			result := r
+			secondary := result.Item.DataType.String()
+			for _, tag := range result.Tags {
+				secondary += "  #" + tag
+			}
+			if result.Field != "" && result.Field != "description" {
+				secondary += "  (" + result.Field + ")"
+			}
+			list.AddItem(tview.Escape(result.Item.Metadata), tview.Escape(secondary), 0, func() {
+				showDataDetails(app, client, result.Item, func() { searchItems(app, client, input.GetText()) })
+			})
+		}
+		text := fmt.Sprintf("%d of %d items", len(results), index.Len())
+		if indexErr != nil {
+			text += fmt.Sprintf("  [red]index incomplete: %v[-]", indexErr)
+		}
+		status.SetText(text)
+	}
+	input.SetChangedFunc(update)
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEscape:
+			actionTypeSelection(app, client)
+		case tcell.KeyEnter, tcell.KeyTab, tcell.KeyDown:
+			if list.GetItemCount() > 0 {
+				app.SetFocus(list)
+			}
+		}
+	})
+	list.SetDoneFunc(func() { app.SetFocus(input) })
+	update(query)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(status, 1, 0, false).
+		AddItem(list, 0, 1, false)
+	layout.SetBorder(true).SetTitle("Search (Esc to go back)").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(layout, true).SetFocus(input)
+}
+
+// getData lists the headers of the stored items of a type. Their data is
+// only downloaded when an item is opened.
 func getData(app *tview.Application, client pb.GophKeeperServiceClient, dataType pb.DataType, actionType uint) {
-	items, err := handlers.GetItems(client, dataType)
+	headers, err := handlers.ListItems(client, dataType)
 	if err != nil {
 		errorModal(app, fmt.Sprintf("Failed to retrieve data: %v", err))
 		return
@@ -229,14 +831,18 @@ func getData(app *tview.Application, client pb.GophKeeperServiceClient, dataType
 
 	list := tview.NewList()
 	num := 1
-	for _, item := range items {
-		itemCopy := item
-		decription := item.Metadata
+	for _, header := range headers {
+		item := &pb.DataItem{Id: header.Id, DataType: header.DataType, Metadata: header.Metadata}
+		decription := header.Metadata
 		if decription == "" {
 			decription = "Item " + fmt.Sprint(num)
 		}
-		list.AddItem(decription, fmt.Sprintf("Type: %v", item.DataType), 0, func() {
-			showDataDetails(app, client, itemCopy)
+		secondary := fmt.Sprintf("Type: %v  ID: %d  Size: %s", header.DataType, header.Id, formatSize(header.Size))
+		if header.ModifiedAt != nil {
+			secondary += "  Modified: " + header.ModifiedAt.AsTime().Local().Format("2006-01-02 15:04")
+		}
+		list.AddItem(decription, secondary, 0, func() {
+			showDataDetails(app, client, item, func() { getData(app, client, dataType, actionType) })
 		})
 		num++
 	}
@@ -249,21 +855,463 @@ func getData(app *tview.Application, client pb.GophKeeperServiceClient, dataType
 	app.SetRoot(list, true).SetFocus(list)
 }
 
-// showDataDetails displays a modal with the selected item's details.
-func showDataDetails(app *tview.Application, client pb.GophKeeperServiceClient, item *pb.DataItem) {
-	dataContent := string(item.Data)
+// showDataDetails displays the selected item's details. Secret fields are
+// masked until revealed and every field can be copied to the clipboard.
+// OTP items show the current code, refreshed every second with a countdown,
+// and notes open in a full-screen Markdown viewer. Items listed without
+// their data are downloaded first. back is called when the user leaves the
+// details.
+func showDataDetails(app *tview.Application, client pb.GophKeeperServiceClient, item *pb.DataItem, back func()) {
+	if item.Data == nil {
+		full, err := handlers.GetItem(client, item.Id)
+		if err != nil {
+			errorModal(app, fmt.Sprintf("Failed to retrieve item: %v", err))
+			return
+		}
+		item = full
+	}
 
-	modal := tview.NewModal().
-		SetText(fmt.Sprintf("Description: %s\n\nData:\n%s", item.Metadata, dataContent)).
-		AddButtons([]string{"Back"}).
+	switch item.DataType {
+	case pb.DataType_TEXT:
+		showNote(app, item, back)
+		return
+	case pb.DataType_OTP:
+		showOTP(app, item, back)
+		return
+	case pb.DataType_SSH_KEY:
+		showSSHKey(app, item, back)
+		return
+	}
+
+	details, err := itemDetails(item)
+	if err != nil {
+		modal := tview.NewModal().
+			SetText(fmt.Sprintf("Description: %s\n\nData:\n%s", item.Metadata, string(item.Data))).
+			AddButtons([]string{"Back"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) { back() })
+		modal.SetBorder(true).SetTitle("Data Details").SetTitleAlign(tview.AlignLeft)
+		app.SetRoot(modal, true).SetFocus(modal)
+		return
+	}
+
+	status := tview.NewTextView().SetDynamicColors(true).
+		SetText("[gray]Enter: copy  r: reveal/hide  Esc: back[-]")
+	list := tview.NewList()
+
+	revealed := make([]bool, len(details))
+	render := func(i int) string {
+		d := details[i]
+		switch {
+		case d.secret && !revealed[i] && d.masked != "":
+			return d.masked
+		case d.secret && !revealed[i]:
+			return mask(d.value)
+		case d.display != "":
+			return tview.Escape(d.display)
+		}
+		return tview.Escape(d.value)
+	}
+	toggle := func(i int) {
+		if i < len(details) && details[i].secret {
+			revealed[i] = !revealed[i]
+			list.SetItemText(i, tview.Escape(details[i].label), render(i))
+		}
+	}
+
+	for i, d := range details {
+		d := d
+		list.AddItem(tview.Escape(d.label), render(i), 0, func() { status.SetText(tview.Escape(copyMessage(app, d.label, d.value))) })
+	}
+	list.AddItem("Reveal all", "Show or hide all secret fields", 0, func() {
+		all := true
+		for i, d := range details {
+			all = all && (!d.secret || revealed[i])
+		}
+		for i, d := range details {
+			if d.secret && revealed[i] == all {
+				toggle(i)
+			}
+		}
+	})
+	list.AddItem("Back", "Return to the list", 'b', back)
+
+	list.SetDoneFunc(back)
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'r' {
+			toggle(list.GetCurrentItem())
+			return nil
+		}
+		return event
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(list, 0, 1, true).
+		AddItem(status, 1, 0, false)
+	layout.SetBorder(true).SetTitle(tview.Escape(item.Metadata)).SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(layout, true).SetFocus(list)
+}
+
+// detail is a field shown in the item details.
+type detail struct {
+	label   string
+	value   string // Value copied to the clipboard
+	display string // Revealed value if different from value
+	masked  string // Masked value if different from the default mask
+	secret  bool
+}
+
+// itemDetails lists the fields of an item with JSON data: the standard fields
+// of its type first, then any other fields and its custom fields.
+func itemDetails(item *pb.DataItem) ([]detail, error) {
+	var data map[string]string
+	if err := json.Unmarshal(item.Data, &data); err != nil {
+		return nil, err
+	}
+
+	var details []detail
+	used := map[string]bool{"metadata": true, "file_data": true, fields.DataKey: true, fields.TemplateKey: true}
+	add := func(label, key string) {
+		if value := data[key]; value != "" && !used[key] {
+			details = append(details, detail{label: label, value: value, secret: fields.IsSecret(key)})
+		}
+		used[key] = true
+	}
+
+	if name := data[fields.TemplateKey]; name != "" {
+		details = append(details, detail{label: "Template", value: name})
+	}
+
+	switch item.DataType {
+	case pb.DataType_CREDENTIALS:
+		add("Login", "login")
+		add("Password", "password")
+		add("URL", "url")
+	case pb.DataType_CARD:
+		if c, err := card.ParseItem(item.Data); err == nil {
+			expiry := c.Expiry.String()
+			if c.Expiry.Expired(time.Now()) {
+				expiry += " (expired)"
+			}
+			details = append(details,
+				detail{label: "Brand", value: string(c.Brand())},
+				detail{label: "Card Number", value: c.Number, display: card.Format(c.Number), masked: c.Masked(), secret: true},
+				detail{label: "Expires", value: c.Expiry.String(), display: expiry},
+			)
+			used[card.FieldNumber], used[card.FieldExpiration], used[card.FieldBrand] = true, true, true
+		}
+		add("Card Number", card.FieldNumber)
+		add("Expires", card.FieldExpiration)
+		add("CVV", card.FieldCVV)
+		add("Cardholder", card.FieldHolder)
+		add("PIN", card.FieldPIN)
+		add("Billing Address", card.FieldBillingAddress)
+	case pb.DataType_BINARY:
+		add("File", "file_path")
+		if size := len(data["file_data"]); size > 0 {
+			details = append(details, detail{label: "Size", value: fmt.Sprintf("%d bytes", size)})
+		}
+	}
+	add("Tags", fields.TagsKey)
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if !used[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add(key, key)
+	}
+
+	custom, err := fields.FromData(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range custom {
+		details = append(details, detail{label: f.Name, value: f.Value, secret: f.Kind == fields.KindHidden})
+	}
+	return details, nil
+}
+
+// showOTP displays the current code of an OTP item, refreshed every second.
+func showOTP(app *tview.Application, item *pb.DataItem, back func()) {
+	key, err := otp.ParseItem(item.Data)
+	if err != nil {
+		errorModal(app, fmt.Sprintf("Invalid OTP item: %v", err))
+		return
+	}
+
+	stop := make(chan struct{})
+	screenCleanup = func() { close(stop) }
+	message := ""
+	modal := tview.NewModal()
+	render := func() { modal.SetText(otpText(item.Metadata, key, time.Now()) + message) }
+	modal.AddButtons([]string{"Copy Code", "Back"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-			getData(app, client, item.DataType, actions["get"])
+			if buttonLabel == "Copy Code" {
+				code, err := key.Code(time.Now())
+				if err == nil {
+					message, err = "\n\n"+copyMessage(app, "Code", code), nil
+				}
+				if err != nil {
+					message = fmt.Sprintf("\n\nFailed to copy code: %v", err)
+				}
+				render()
+				return
+			}
+			leaveScreen()
+			back()
 		})
+	render()
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				app.QueueUpdateDraw(render)
+			}
+		}
+	}()
 
 	modal.SetBorder(true).SetTitle("Data Details").SetTitleAlign(tview.AlignLeft)
 	app.SetRoot(modal, true).SetFocus(modal)
 }
 
+// showSSHKey displays the public parts of an SSH key item.
+func showSSHKey(app *tview.Application, item *pb.DataItem, back func()) {
+	modal := tview.NewModal().SetText(sshKeyText(item))
+	modal.AddButtons([]string{"Copy Public Key", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Copy Public Key" {
+				key, err := sshkey.ParseItem(item.Data)
+				if err == nil {
+					modal.SetText(sshKeyText(item) + "\n\n" + copyMessage(app, "Public key", key.PublicKey))
+				}
+				return
+			}
+			back()
+		})
+	modal.SetBorder(true).SetTitle("Data Details").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(modal, true).SetFocus(modal)
+}
+
+// clip is the clipboard used by the TUI, created on first use.
+var clip struct {
+	sync.Once
+	*clipboard.Clipboard
+	err error
+}
+
+// copyMessage copies value to the clipboard and describes the result.
+func copyMessage(app *tview.Application, label, value string) string {
+	initClipboard(app)
+	if clip.err != nil {
+		return fmt.Sprintf("Clipboard unavailable: %v", clip.err)
+	}
+	if err := clip.Copy(value); err != nil {
+		return fmt.Sprintf("Failed to copy %s: %v", label, err)
+	}
+	message := fmt.Sprintf("%s copied to the %s clipboard", label, clip.Backend().Name())
+	if clip.Timeout() > 0 {
+		message += fmt.Sprintf(", clearing in %s", clip.Timeout())
+	}
+	return message
+}
+
+// initClipboard configures the clipboard for the TUI. OSC 52 sequences are
+// written to the controlling terminal and delayed clears run on the UI
+// goroutine so they do not interleave with screen updates.
+func initClipboard(app *tview.Application) {
+	clip.Do(func() {
+		var tty io.Writer = os.Stdout
+		if f, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+			tty = f
+		}
+		backend, err := clipboard.Detect(profile.UI.Clipboard, tty)
+		if err != nil {
+			clip.err = err
+			return
+		}
+		clip.Clipboard = clipboard.New(backend, time.Duration(profile.Timeouts.Clipboard))
+		clip.Dispatch = func(f func()) { app.QueueUpdate(f) }
+	})
+}
+
+// Close clears a secret still on the clipboard. It is called when the TUI exits.
+func Close() error {
+	if clip.Clipboard == nil {
+		return nil
+	}
+	return clip.Close()
+}
+
+// showNote displays a TEXT item full screen with its Markdown rendered.
+func showNote(app *tview.Application, item *pb.DataItem, back func()) {
+	var data map[string]string
+	if err := json.Unmarshal(item.Data, &data); err != nil {
+		data = map[string]string{"text": string(item.Data)}
+	}
+	custom, _ := customFieldLines(data, false)
+	rendered := markdown.Render(data["text"])
+	if custom != "" {
+		rendered += "\n\n[gray]" + strings.Repeat("─", 40) + "[-]\n" + tview.Escape(custom)
+	}
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true).
+		SetWordWrap(true).
+		SetText(rendered)
+	view.SetBorder(true).SetTitle(item.Metadata).SetTitleAlign(tview.AlignLeft)
+
+	raw := !profile.UI.RawNotes
+	buttons := tview.NewForm()
+	toggle := func() {
+		raw = !raw
+		if raw {
+			view.SetText(tview.Escape(data["text"]))
+			buttons.GetButton(0).SetLabel("Rendered")
+		} else {
+			view.SetText(rendered)
+			buttons.GetButton(0).SetLabel("Raw")
+		}
+	}
+	buttons.AddButton("Raw", toggle)
+	toggle() // Show the preferred view first
+	buttons.AddButton("Back", back)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(view, 0, 1, false).
+		AddItem(buttons, 3, 0, true)
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab && buttons.HasFocus() {
+			app.SetFocus(view)
+			return nil
+		}
+		if event.Key() == tcell.KeyTab || event.Key() == tcell.KeyEscape {
+			app.SetFocus(buttons)
+			return nil
+		}
+		return event
+	})
+
+	app.SetRoot(layout, true).SetFocus(buttons)
+}
+
+// otpText renders the current one-time password with a countdown bar.
+func otpText(description string, key *otp.Key, now time.Time) string {
+	code, err := key.Code(now)
+	if err != nil {
+		return fmt.Sprintf("Description: %s\n\nFailed to compute code: %v", description, err)
+	}
+	remaining := key.Remaining(now)
+	width := 20
+	filled := int(remaining * time.Duration(width) / key.Period)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+	return fmt.Sprintf("Description: %s\n%s\n\nCode: %s\n\n%s %2ds", description, key.Label(), code, bar, int(remaining.Seconds()))
+}
+
+// customFieldLines renders the custom fields of item data, one per line.
+func customFieldLines(data map[string]string, reveal bool) (string, bool) {
+	custom, err := fields.FromData(data)
+	if err != nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	hidden := false
+	for _, f := range custom {
+		value := f.Value
+		if f.Kind == fields.KindHidden && value != "" {
+			hidden = true
+			if !reveal {
+				value = mask(value)
+			}
+		}
+		fmt.Fprintf(&b, "%s: %s\n", f.Name, value)
+	}
+	return b.String(), hidden
+}
+
+// mask hides a secret value, keeping empty values empty.
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "********"
+}
+
+// showUsage displays the storage used by the account and its quotas.
+func showUsage(app *tview.Application, client pb.GophKeeperServiceClient) {
+	usage, err := handlers.GetUsage(client)
+	if err != nil {
+		errorModal(app, fmt.Sprintf("Failed to retrieve usage: %v", err))
+		return
+	}
+
+	modal := tview.NewModal().
+		SetText(usageText(usage)).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) { actionTypeSelection(app, client) })
+	modal.SetBorder(true).SetTitle("Storage Usage").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(modal, true).SetFocus(modal)
+}
+
+// usageText describes the usage and quotas, unlimited when a quota is 0.
+func usageText(usage *pb.GetUsageResponse) string {
+	items, storage := fmt.Sprintf("%d items", usage.Items), formatSize(usage.Bytes)
+	if usage.MaxItems > 0 {
+		items += fmt.Sprintf(" of %d (%d%%)", usage.MaxItems, usage.Items*100/usage.MaxItems)
+	}
+	if usage.MaxBytes > 0 {
+		storage += fmt.Sprintf(" of %s (%d%%)", formatSize(usage.MaxBytes), usage.Bytes*100/usage.MaxBytes)
+	}
+	return fmt.Sprintf("Items: %s\nStorage: %s\nLargest item: %s", items, storage, formatSize(usage.MaxItemSize))
+}
+
+// formatSize formats a size in bytes for lists.
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// confirmModal asks the user to confirm an action, returning to form on cancel.
+func confirmModal(app *tview.Application, form *tview.Form, message string, onConfirm func()) {
+	modal := tview.NewModal().
+		SetText(message).
+		AddButtons([]string{"Continue", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Continue" {
+				onConfirm()
+				return
+			}
+			app.SetRoot(form, true)
+		})
+
+	modal.SetBorder(true).SetTitle("Warning").SetTitleAlign(tview.AlignLeft)
+	app.SetRoot(modal, true)
+}
+
+// sshKeyText renders the public parts of an SSH key item.
+func sshKeyText(item *pb.DataItem) string {
+	key, err := sshkey.ParseItem(item.Data)
+	if err != nil {
+		return fmt.Sprintf("Description: %s\n\nInvalid SSH key item: %v", item.Metadata, err)
+	}
+	return fmt.Sprintf("Description: %s\n\nComment: %s\nFingerprint: %s\n\nPublic key:\n%s\n\nServe this key with `gophkeeper ssh-agent`.",
+		item.Metadata, key.Comment, key.Fingerprint, key.PublicKey)
+}
+
 // errorModal displays an error message in a modal.
 func errorModal(app *tview.Application, message string) {
 	modal := tview.NewModal().
@@ -290,6 +1338,18 @@ func promptForFilePath() (string, error) {
 	return prompt.Run()
 }
 
+// buildSSHKey imports the private key at keyFile, or generates a new key if keyFile is empty.
+func buildSSHKey(keyFile, passphrase, comment string) (*sshkey.Key, error) {
+	if keyFile == "" {
+		return sshkey.Generate(comment)
+	}
+	pemBytes, err := readBinaryFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return sshkey.Import(pemBytes, passphrase, comment)
+}
+
 func readBinaryFile(filePath string) ([]byte, error) {
 	if filePath == "" {
 		return nil, fmt.Errorf("file path is empty")
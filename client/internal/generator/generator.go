@@ -0,0 +1,205 @@
+// Package generator creates random passwords and diceware passphrases.
+//
+// All randomness comes from crypto/rand. Passphrases are built from the EFF
+// large word list, which is embedded into the binary.
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Character classes used for passwords.
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+	// ambiguousChars are easily confused when read or typed by hand.
+	ambiguousChars = "Il1O0o|`'\";:,."
+)
+
+// MinLength and MaxLength bound the password length.
+const (
+	MinLength = 4
+	MaxLength = 256
+)
+
+// Options configures password generation.
+type Options struct {
+	Length           int  // Number of characters
+	Lower            bool // Include lowercase letters
+	Upper            bool // Include uppercase letters
+	Digits           bool // Include digits
+	Symbols          bool // Include punctuation symbols
+	ExcludeAmbiguous bool // Leave out characters such as l, 1, O and 0
+}
+
+// DefaultOptions returns the settings used by the "Generate" button.
+func DefaultOptions() Options {
+	return Options{Length: 20, Lower: true, Upper: true, Digits: true, Symbols: true}
+}
+
+// PassphraseOptions configures passphrase generation.
+type PassphraseOptions struct {
+	Words      int    // Number of words
+	Separator  string // Placed between words
+	Capitalize bool   // Capitalize the first letter of each word
+}
+
+// DefaultPassphraseOptions returns six lowercase words separated by dashes.
+func DefaultPassphraseOptions() PassphraseOptions {
+	return PassphraseOptions{Words: 6, Separator: "-"}
+}
+
+//go:embed eff_large_wordlist.txt
+var effWordList []byte
+
+// words parses the embedded word list once.
+var words = sync.OnceValue(func() []string {
+	list := make([]string, 0, 7776)
+	scanner := bufio.NewScanner(bytes.NewReader(effWordList))
+	for scanner.Scan() {
+		if _, word, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			list = append(list, word)
+		}
+	}
+	return list
+})
+
+// Password generates a random password containing at least one character of
+// every enabled class.
+func Password(opts Options) (string, error) {
+	if opts.Length < MinLength || opts.Length > MaxLength {
+		return "", fmt.Errorf("password length must be between %d and %d", MinLength, MaxLength)
+	}
+
+	classes := opts.classes()
+	if len(classes) == 0 {
+		return "", fmt.Errorf("at least one character class must be enabled")
+	}
+	if opts.Length < len(classes) {
+		return "", fmt.Errorf("password length %d is too short for %d character classes", opts.Length, len(classes))
+	}
+
+	all := strings.Join(classes, "")
+	password := make([]byte, 0, opts.Length)
+	for _, class := range classes {
+		c, err := pick(class)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	for len(password) < opts.Length {
+		c, err := pick(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// Shuffle so the guaranteed characters are not always at the start.
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+// Passphrase generates a diceware passphrase from the embedded word list.
+func Passphrase(opts PassphraseOptions) (string, error) {
+	if opts.Words < 1 || opts.Words > 64 {
+		return "", fmt.Errorf("passphrase must have between 1 and 64 words")
+	}
+
+	list := words()
+	chosen := make([]string, opts.Words)
+	for i := range chosen {
+		n, err := randInt(len(list))
+		if err != nil {
+			return "", err
+		}
+		word := list[n]
+		if opts.Capitalize {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			word = string(runes)
+		}
+		chosen[i] = word
+	}
+	return strings.Join(chosen, opts.Separator), nil
+}
+
+// Entropy returns the strength in bits of a password generated with opts.
+func (opts Options) Entropy() float64 {
+	alphabet := len(strings.Join(opts.classes(), ""))
+	if alphabet == 0 {
+		return 0
+	}
+	return float64(opts.Length) * math.Log2(float64(alphabet))
+}
+
+// Entropy returns the strength in bits of a passphrase generated with opts.
+func (opts PassphraseOptions) Entropy() float64 {
+	return float64(opts.Words) * math.Log2(float64(len(words())))
+}
+
+// classes returns the enabled character sets, without ambiguous characters if requested.
+func (opts Options) classes() []string {
+	var classes []string
+	for _, class := range []struct {
+		enabled bool
+		chars   string
+	}{
+		{opts.Lower, lowerChars},
+		{opts.Upper, upperChars},
+		{opts.Digits, digitChars},
+		{opts.Symbols, symbolChars},
+	} {
+		if !class.enabled {
+			continue
+		}
+		chars := class.chars
+		if opts.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguousChars, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		classes = append(classes, chars)
+	}
+	return classes
+}
+
+// pick returns a uniformly random character of chars.
+func pick(chars string) (byte, error) {
+	n, err := randInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n], nil
+}
+
+// randInt returns a uniformly random integer in [0, n).
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPasswordLengthAndClasses ensures every enabled class is present
+func TestPasswordLengthAndClasses(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		password, err := Password(DefaultOptions())
+		require.NoError(t, err)
+		assert.Len(t, password, 20)
+		assert.True(t, strings.ContainsAny(password, lowerChars), "Password should contain a lowercase letter")
+		assert.True(t, strings.ContainsAny(password, upperChars), "Password should contain an uppercase letter")
+		assert.True(t, strings.ContainsAny(password, digitChars), "Password should contain a digit")
+		assert.True(t, strings.ContainsAny(password, symbolChars), "Password should contain a symbol")
+	}
+}
+
+// TestPasswordExcludeAmbiguous ensures ambiguous characters are never used
+func TestPasswordExcludeAmbiguous(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Length = 200
+	opts.ExcludeAmbiguous = true
+
+	for i := 0; i < 20; i++ {
+		password, err := Password(opts)
+		require.NoError(t, err)
+		assert.False(t, strings.ContainsAny(password, ambiguousChars), "Password should not contain ambiguous characters")
+	}
+}
+
+// TestPasswordDigitsOnly ensures a single class can be used
+func TestPasswordDigitsOnly(t *testing.T) {
+	password, err := Password(Options{Length: 6, Digits: true})
+	require.NoError(t, err)
+	assert.Equal(t, "", strings.Trim(password, digitChars), "PIN should contain only digits")
+}
+
+// TestPasswordInvalidOptions ensures invalid settings are rejected
+func TestPasswordInvalidOptions(t *testing.T) {
+	_, err := Password(Options{Length: 20})
+	assert.Error(t, err, "No character classes should be rejected")
+
+	_, err = Password(Options{Length: 2, Lower: true})
+	assert.Error(t, err, "Too short passwords should be rejected")
+
+	_, err = Password(Options{Length: MaxLength + 1, Lower: true})
+	assert.Error(t, err, "Too long passwords should be rejected")
+}
+
+// TestPassphrase ensures passphrases use words from the embedded list
+func TestPassphrase(t *testing.T) {
+	require.Len(t, words(), 7776, "EFF large word list should have 7776 words")
+
+	phrase, err := Passphrase(PassphraseOptions{Words: 5, Separator: " ", Capitalize: true})
+	require.NoError(t, err)
+
+	parts := strings.Split(phrase, " ")
+	require.Len(t, parts, 5)
+	for _, part := range parts {
+		assert.Equal(t, strings.ToUpper(part[:1]), part[:1], "Words should be capitalized")
+		assert.Contains(t, words(), strings.ToLower(part[:1])+part[1:])
+	}
+
+	_, err = Passphrase(PassphraseOptions{Words: 0})
+	assert.Error(t, err)
+}
+
+// TestEntropy ensures entropy estimates match the alphabet sizes
+func TestEntropy(t *testing.T) {
+	assert.InDelta(t, 6*3.3219, Options{Length: 6, Digits: true}.Entropy(), 0.01)
+	assert.InDelta(t, 6*12.925, DefaultPassphraseOptions().Entropy(), 0.01)
+}
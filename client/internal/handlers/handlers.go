@@ -13,24 +13,40 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
+	"github.com/golangTroshin/gophkeeper/client/internal/card"
+	"github.com/golangTroshin/gophkeeper/client/internal/fields"
 	pb "github.com/golangTroshin/gophkeeper/grpc"
 	"github.com/rivo/tview"
 	"golang.org/x/crypto/pbkdf2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Session stores the user authentication token.
 type Session struct {
 	UserToken string
+	Username  string // Kept while locked so the user can unlock
+	key       []byte // Encryption key derived from the master seed on first use
 }
 
 // Global session instance.
 var session = &Session{}
 
+// RequestTimeout bounds every call to the server.
+var RequestTimeout = 5 * time.Second
+
+// ErrSessionRejected is returned when the server no longer accepts the session token.
+var ErrSessionRejected = errors.New("session rejected by the server")
+
+// ErrQuotaExceeded is returned when an item does not fit the user's storage quota.
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
 // Login authenticates a user and retrieves a session token.
 func Login(client pb.GophKeeperServiceClient, username, password string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
 	defer cancel()
 
 	res, err := client.AuthenticateUser(ctx, &pb.AuthenticateUserRequest{
@@ -46,13 +62,100 @@ func Login(client pb.GophKeeperServiceClient, username, password string) error {
 		return fmt.Errorf("%s", res.Message)
 	}
 
-	session.UserToken = res.Token
+	Resume(username, res.Token, nil)
+	saveSession()
 	return nil
 }
 
+// Lock forgets the session token, also the saved one. The username is kept
+// so the vault can be unlocked by logging in again.
+func Lock() {
+	clearSession()
+	forgetSavedSession()
+}
+
+// clearSession wipes the session token and key from memory.
+func clearSession() {
+	session.UserToken = ""
+	wipe(session.key)
+	session.key = nil
+}
+
+// Logout forgets the session.
+func Logout() {
+	Lock()
+	session.Username = ""
+}
+
+// Resume restores a session kept by the client agent, so the user does not
+// have to log in again. key may be nil to derive it on first use.
+func Resume(username, token string, key []byte) {
+	clearSession()
+	session.Username = username
+	session.UserToken = token
+	if key != nil {
+		session.key = append([]byte(nil), key...)
+	}
+}
+
+// Token returns the session token, or an empty string if not logged in.
+func Token() string {
+	return session.UserToken
+}
+
+// SessionKey returns a copy of the session's encryption key, deriving it from
+// the master seed if needed. The caller should wipe the copy after use.
+func SessionKey(client pb.GophKeeperServiceClient) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+	return sessionKey(ctx, client)
+}
+
+// sessionKey returns a copy of the cached encryption key, retrieving the
+// master seed and deriving the key on first use.
+func sessionKey(ctx context.Context, client pb.GophKeeperServiceClient) ([]byte, error) {
+	if session.UserToken == "" {
+		return nil, fmt.Errorf("user is not authenticated")
+	}
+	if session.key == nil {
+		key, err := seedKey(ctx, client, session.UserToken)
+		if err != nil {
+			return nil, err
+		}
+		session.key = key
+	}
+	return append([]byte(nil), session.key...), nil
+}
+
+// TokenKey retrieves the master seed for token and derives its encryption
+// key. Unlike SessionKey it does not touch the global session, so it is safe
+// to call from a goroutine. The caller should wipe the key after use.
+func TokenKey(client pb.GophKeeperServiceClient, token string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+	return seedKey(ctx, client, token)
+}
+
+// seedKey retrieves the master seed for token and derives the key from it.
+func seedKey(ctx context.Context, client pb.GophKeeperServiceClient, token string) ([]byte, error) {
+	res, err := client.MasterSeedRetrieve(ctx, &pb.MasterSeedRetrieveRequest{Token: token})
+	if err != nil {
+		return nil, err
+	}
+	if !res.Success {
+		return nil, fmt.Errorf("%w: %s", ErrSessionRejected, res.Message)
+	}
+	return DeriveKeyFromSeed(res.MasterSeed), nil
+}
+
+// Username returns the name of the logged in or locked user.
+func Username() string {
+	return session.Username
+}
+
 // SignUp registers a new user and saves their master seed for encryption.
 func SignUp(client pb.GophKeeperServiceClient, username, password, seed string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
 	defer cancel()
 
 	res, err := client.RegisterUser(ctx, &pb.RegisterUserRequest{
@@ -69,7 +172,8 @@ func SignUp(client pb.GophKeeperServiceClient, username, password, seed string)
 		return fmt.Errorf("%s", res.Message)
 	}
 
-	session.UserToken = res.Token
+	Resume(username, res.Token, nil)
+	saveSession()
 	return nil
 }
 
@@ -82,21 +186,64 @@ func CollectFormData(form *tview.Form, dataType pb.DataType) map[string]string {
 		data["login"] = form.GetFormItemByLabel("Login").(*tview.InputField).GetText()
 		data["password"] = form.GetFormItemByLabel("Password").(*tview.InputField).GetText()
 	case pb.DataType_TEXT:
-		data["text"] = form.GetFormItemByLabel("Text").(*tview.InputField).GetText()
+		data["text"] = form.GetFormItemByLabel("Text").(*tview.TextArea).GetText()
 	case pb.DataType_BINARY:
 		data["file_path"] = form.GetFormItemByLabel("Selected File").(*tview.InputField).GetText()
 	case pb.DataType_CARD:
-		data["card_number"] = form.GetFormItemByLabel("Card Number").(*tview.InputField).GetText()
-		data["expiration_date"] = form.GetFormItemByLabel("Expiration Date").(*tview.InputField).GetText()
-		data["cvv"] = form.GetFormItemByLabel("CVV").(*tview.InputField).GetText()
+		data[card.FieldNumber] = form.GetFormItemByLabel("Card Number").(*tview.InputField).GetText()
+		data[card.FieldExpiration] = form.GetFormItemByLabel("Expiration Date").(*tview.InputField).GetText()
+		data[card.FieldCVV] = form.GetFormItemByLabel("CVV").(*tview.InputField).GetText()
+		data[card.FieldHolder] = form.GetFormItemByLabel("Cardholder").(*tview.InputField).GetText()
+		data[card.FieldPIN] = form.GetFormItemByLabel("PIN").(*tview.InputField).GetText()
+		data[card.FieldBillingAddress] = form.GetFormItemByLabel("Billing Address").(*tview.InputField).GetText()
+	case pb.DataType_OTP:
+		data["uri"] = form.GetFormItemByLabel("OTP URI").(*tview.InputField).GetText()
+	case pb.DataType_SSH_KEY:
+		data["comment"] = form.GetFormItemByLabel("Comment").(*tview.InputField).GetText()
+		data["key_file"] = form.GetFormItemByLabel("Private Key File").(*tview.InputField).GetText()
+		data["passphrase"] = form.GetFormItemByLabel("Key Passphrase").(*tview.InputField).GetText()
 	}
 	data["metadata"] = form.GetFormItemByLabel("Description").(*tview.InputField).GetText()
+	if tags := fields.ParseTags(form.GetFormItemByLabel("Tags").(*tview.InputField).GetText()); len(tags) > 0 {
+		data[fields.TagsKey] = strings.Join(tags, ", ")
+	}
 	return data
 }
 
+// CollectCustomFields retrieves the values of custom fields added to the form.
+func CollectCustomFields(form *tview.Form, specs []fields.Spec) []fields.Field {
+	custom := make([]fields.Field, 0, len(specs))
+	for _, spec := range specs {
+		custom = append(custom, fields.Field{
+			Name:  spec.Name,
+			Kind:  spec.Kind,
+			Value: form.GetFormItemByLabel(spec.Label()).(*tview.InputField).GetText(),
+		})
+	}
+	return custom
+}
+
+// GetTemplates returns the built-in item templates followed by the user's own templates.
+func GetTemplates(client pb.GophKeeperServiceClient) ([]fields.Template, error) {
+	templates := append([]fields.Template{}, fields.Builtin...)
+
+	items, err := GetItems(client, pb.DataType_TEMPLATE)
+	if err != nil {
+		return templates, err
+	}
+	for _, item := range items {
+		t, err := fields.ParseTemplateItem(item.Data)
+		if err != nil {
+			continue // skip templates written by newer clients
+		}
+		templates = append(templates, *t)
+	}
+	return templates, nil
+}
+
 // SaveData encrypts user data and sends it to the server for storage.
 func SaveData(client pb.GophKeeperServiceClient, app *tview.Application, dataType pb.DataType, data map[string]string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
 	defer cancel()
 
 	if session.UserToken == "" {
@@ -111,12 +258,13 @@ func SaveData(client pb.GophKeeperServiceClient, app *tview.Application, dataTyp
 		return err
 	}
 
-	res, err := client.MasterSeedRetrieve(ctx, &pb.MasterSeedRetrieveRequest{Token: session.UserToken})
-	if err != nil || !res.Success {
+	key, err := sessionKey(ctx, client)
+	if err != nil {
 		return err
 	}
-	key := DeriveKeyFromSeed(string(res.MasterSeed))
+	defer wipe(key)
 	encryptedData, err := encryptData(bytes, key)
+	wipe(bytes)
 	if err != nil {
 		return err
 	}
@@ -130,12 +278,15 @@ func SaveData(client pb.GophKeeperServiceClient, app *tview.Application, dataTyp
 		Metadata: metaData,
 	})
 
+	if status.Code(err) == codes.ResourceExhausted {
+		return fmt.Errorf("%w: %s", ErrQuotaExceeded, status.Convert(err).Message())
+	}
 	if err != nil {
 		return err
 	}
 
-	if !res.Success {
-		return fmt.Errorf("failed to save data: %v ||| %v", err, resp.Message)
+	if !resp.Success {
+		return fmt.Errorf("failed to save data: %s", resp.Message)
 	}
 
 	return nil
@@ -147,6 +298,13 @@ func DeriveKeyFromSeed(seed string) []byte {
 	return pbkdf2.Key([]byte(seed), salt, 4096, 32, sha256.New)
 }
 
+// wipe overwrites key material that is no longer needed.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
 // encryptData encrypts plaintext data using AES-GCM.
 func encryptData(data []byte, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
@@ -199,38 +357,135 @@ func DecryptData(encryptedText string, key []byte) (string, error) {
 	return string(plaintext), nil
 }
 
-// GetItems retrieves encrypted data from the server, decrypts it, and returns the items.
+// GetItems retrieves encrypted data of a type from the server, decrypts
+// it, and returns the items.
 func GetItems(client pb.GophKeeperServiceClient, dataType pb.DataType) ([]*pb.DataItem, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	return retrieveItems(client, &pb.RetrieveDataRequest{Types: []pb.DataType{dataType}})
+}
 
-	items := []*pb.DataItem{}
+// GetAllItems retrieves and decrypts the items of every data type in one
+// paged request.
+func GetAllItems(client pb.GophKeeperServiceClient) ([]*pb.DataItem, error) {
+	return retrieveItems(client, &pb.RetrieveDataRequest{AllTypes: true})
+}
 
-	res, err := client.RetrieveData(ctx, &pb.RetrieveDataRequest{
-		Token:  session.UserToken,
-		Filter: dataType,
-	})
+// GetAllItemsWith is GetAllItems for the session given by token and key. It
+// does not touch the global session, so it is safe to call from a goroutine
+// while the session is locked or replaced.
+func GetAllItemsWith(client pb.GophKeeperServiceClient, token string, key []byte) ([]*pb.DataItem, error) {
+	all, err := retrievePages(client, &pb.RetrieveDataRequest{Token: token, AllTypes: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := decryptWith(all, key); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// retrieveItems runs req page by page and decrypts the items.
+func retrieveItems(client pb.GophKeeperServiceClient, req *pb.RetrieveDataRequest) ([]*pb.DataItem, error) {
+	items := []*pb.DataItem{}
 
+	req.Token = session.UserToken
+	all, err := retrievePages(client, req)
 	if err != nil {
 		return items, err
 	}
 
-	var resSeed *pb.MasterSeedRetrieveResponse
-	resSeed, err = client.MasterSeedRetrieve(ctx, &pb.MasterSeedRetrieveRequest{Token: session.UserToken})
-	if err != nil || !resSeed.Success {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+	if err := decryptItems(ctx, client, all); err != nil {
 		return items, err
 	}
+	return all, nil
+}
+
+// retrievePages runs req page by page. Every page gets its own
+// RequestTimeout, so large vaults are not cut off.
+func retrievePages(client pb.GophKeeperServiceClient, req *pb.RetrieveDataRequest) ([]*pb.DataItem, error) {
+	var all []*pb.DataItem
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+		res, err := client.RetrieveData(ctx, req)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, res.Items...)
+		if res.NextPageToken == "" {
+			return all, nil
+		}
+		req.PageToken = res.NextPageToken
+	}
+}
+
+// ListItems retrieves the headers of the items of a type, without their
+// data, newest first.
+func ListItems(client pb.GophKeeperServiceClient, dataType pb.DataType) ([]*pb.ItemHeader, error) {
+	req := &pb.ListItemsRequest{
+		Token: session.UserToken,
+		Types: []pb.DataType{dataType},
+		Sort:  pb.SortOrder_CREATED_DESC,
+	}
+	var headers []*pb.ItemHeader
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+		res, err := client.ListItems(ctx, req)
+		cancel()
+		if err != nil {
+			return headers, err
+		}
+		headers = append(headers, res.Items...)
+		if res.NextPageToken == "" {
+			return headers, nil
+		}
+		req.PageToken = res.NextPageToken
+	}
+}
+
+// GetItem retrieves and decrypts a single item.
+func GetItem(client pb.GophKeeperServiceClient, id uint64) (*pb.DataItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	res, err := client.GetItem(ctx, &pb.GetItemRequest{Token: session.UserToken, Id: id})
+	if err != nil {
+		return nil, err
+	}
+	if err := decryptItems(ctx, client, []*pb.DataItem{res.Item}); err != nil {
+		return nil, err
+	}
+	return res.Item, nil
+}
+
+// GetUsage retrieves the storage used by the user and the server limits.
+func GetUsage(client pb.GophKeeperServiceClient) (*pb.GetUsageResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	return client.GetUsage(ctx, &pb.GetUsageRequest{Token: session.UserToken})
+}
 
-	key := DeriveKeyFromSeed(resSeed.MasterSeed)
+// decryptItems replaces the data of items with its plaintext.
+func decryptItems(ctx context.Context, client pb.GophKeeperServiceClient, items []*pb.DataItem) error {
+	key, err := sessionKey(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer wipe(key)
+	return decryptWith(items, key)
+}
 
-	for _, item := range res.Items {
+// decryptWith replaces the data of items with its plaintext decrypted with key.
+func decryptWith(items []*pb.DataItem, key []byte) error {
+	for _, item := range items {
 		decryptedData, err := DecryptData(base64.StdEncoding.EncodeToString(item.Data), key)
 		if err != nil {
-			return items, err
+			return err
 		}
 
 		item.Data = []byte(decryptedData)
 	}
-
-	return res.Items, nil
+	return nil
 }
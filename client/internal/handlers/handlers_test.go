@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/base64"
+	"strconv"
 	"testing"
+	"time"
 
+	pb "github.com/golangTroshin/gophkeeper/grpc"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
 )
 
 // Mock data for testing
@@ -57,3 +63,77 @@ func TestSessionToken(t *testing.T) {
 	session.UserToken = "mock_token"
 	assert.Equal(t, "mock_token", session.UserToken, "Session token should be stored correctly")
 }
+
+// TestLock ensures locking forgets the token but keeps the username
+func TestLock(t *testing.T) {
+	session.UserToken, session.Username = "mock_token", "alice"
+
+	Lock()
+	assert.Empty(t, session.UserToken, "Token should be wiped when locked")
+	assert.Equal(t, "alice", Username(), "Username should be kept for unlocking")
+
+	Logout()
+	assert.Empty(t, Username())
+}
+
+// TestWipe ensures key material is zeroed
+func TestWipe(t *testing.T) {
+	key := DeriveKeyFromSeed(mockSeed)
+	wipe(key)
+	assert.Equal(t, make([]byte, 32), key)
+}
+
+// TestResume ensures a resumed session keeps a private copy of the key
+func TestResume(t *testing.T) {
+	key := DeriveKeyFromSeed(mockSeed)
+	Resume("alice", "mock_token", key)
+	wipe(key)
+
+	assert.Equal(t, "mock_token", Token())
+	assert.Equal(t, DeriveKeyFromSeed(mockSeed), session.key, "Wiping the caller's key should not affect the session")
+
+	cached := session.key
+	Lock()
+	assert.Nil(t, session.key)
+	assert.Equal(t, make([]byte, 32), cached, "Locking should wipe the cached key")
+}
+
+// pagedClient serves RetrieveData in slow pages of one encrypted item each
+type pagedClient struct {
+	pb.GophKeeperServiceClient
+	pages int
+	delay time.Duration
+	data  []byte
+}
+
+// RetrieveData returns the page after req.PageToken once the delay passed
+func (c *pagedClient) RetrieveData(ctx context.Context, req *pb.RetrieveDataRequest, _ ...grpc.CallOption) (*pb.RetrieveDataResponse, error) {
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	page, _ := strconv.Atoi(req.PageToken)
+	res := &pb.RetrieveDataResponse{Items: []*pb.DataItem{{Id: uint64(page), Data: append([]byte(nil), c.data...)}}}
+	if page+1 < c.pages {
+		res.NextPageToken = strconv.Itoa(page + 1)
+	}
+	return res, nil
+}
+
+// TestGetAllItemsPageTimeout ensures the request timeout applies to each page, not the whole vault
+func TestGetAllItemsPageTimeout(t *testing.T) {
+	timeout := RequestTimeout
+	RequestTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { RequestTimeout = timeout })
+
+	key := DeriveKeyFromSeed(mockSeed)
+	encrypted, err := encryptData([]byte(mockData), key)
+	require.NoError(t, err)
+
+	client := &pagedClient{pages: 4, delay: 40 * time.Millisecond, data: encrypted}
+	items, err := GetAllItemsWith(client, "mock_token", key)
+	require.NoError(t, err)
+	require.Len(t, items, 4)
+	assert.Equal(t, mockData, string(items[3].Data))
+}
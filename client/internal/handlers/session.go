@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golangTroshin/gophkeeper/client/internal/keyring"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// ErrNoSavedSession is returned by ResumeSaved when there is no session to resume.
+var ErrNoSavedSession = errors.New("no saved session")
+
+// expiryMargin is how long before its expiry a saved session is no longer resumed.
+const expiryMargin = time.Minute
+
+// persist is where sessions are kept between client runs, see PersistSessions.
+var persist struct {
+	keyring *keyring.Keyring
+	name    string
+}
+
+// savedSession is the persisted form of a session.
+type savedSession struct {
+	Username string    `json:"username"`
+	Token    string    `json:"token"`
+	Expires  time.Time `json:"expires"`
+}
+
+// PersistSessions keeps the sessions of a profile in kr, so later client runs
+// can resume them with ResumeSaved instead of logging in again.
+func PersistSessions(kr *keyring.Keyring, profile string) {
+	persist.keyring = kr
+	persist.name = "session-" + profile
+}
+
+// ResumeSaved restores the saved session if it has not expired and the
+// server still accepts it. Rejected sessions are forgotten.
+func ResumeSaved(client pb.GophKeeperServiceClient) error {
+	if persist.keyring == nil {
+		return ErrNoSavedSession
+	}
+	data, err := persist.keyring.Get(persist.name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNoSavedSession
+	}
+	if err != nil {
+		forgetSavedSession()
+		return err
+	}
+
+	var saved savedSession
+	err = json.Unmarshal(data, &saved)
+	wipe(data)
+	if err != nil || saved.Token == "" {
+		forgetSavedSession()
+		return ErrNoSavedSession
+	}
+	if time.Now().After(saved.Expires.Add(-expiryMargin)) {
+		forgetSavedSession()
+		return ErrNoSavedSession
+	}
+
+	Resume(saved.Username, saved.Token, nil)
+	key, err := SessionKey(client) // Checks the token and caches the key
+	if err != nil {
+		clearSession()
+		if errors.Is(err, ErrSessionRejected) {
+			forgetSavedSession()
+		}
+		return err
+	}
+	wipe(key)
+	return nil
+}
+
+// saveSession persists the current session if PersistSessions was called.
+// A session that cannot be saved only means logging in again next time.
+func saveSession() {
+	if persist.keyring == nil {
+		return
+	}
+	expires, err := tokenExpiry(session.UserToken)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(savedSession{Username: session.Username, Token: session.UserToken, Expires: expires})
+	if err != nil {
+		return
+	}
+	_ = persist.keyring.Set(persist.name, data)
+	wipe(data)
+}
+
+// forgetSavedSession deletes the persisted session.
+func forgetSavedSession() {
+	if persist.keyring != nil {
+		_ = persist.keyring.Delete(persist.name)
+	}
+}
+
+// tokenExpiry reads the expiry of a session token. The signature is checked
+// by the server, the client only needs to know when to stop resuming it.
+func tokenExpiry(token string) (time.Time, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}, err
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("session token has no expiry")
+	}
+	return time.Unix(int64(exp), 0), nil
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golangTroshin/gophkeeper/client/internal/keyring"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// seedClient answers master seed requests for a single valid token
+type seedClient struct {
+	pb.GophKeeperServiceClient
+	token string
+}
+
+// MasterSeedRetrieve returns the mock seed for the valid token
+func (c *seedClient) MasterSeedRetrieve(_ context.Context, req *pb.MasterSeedRetrieveRequest, _ ...grpc.CallOption) (*pb.MasterSeedRetrieveResponse, error) {
+	if req.Token != c.token {
+		return &pb.MasterSeedRetrieveResponse{Success: false, Message: "Unauthorized"}, nil
+	}
+	return &pb.MasterSeedRetrieveResponse{Success: true, MasterSeed: mockSeed}, nil
+}
+
+// newToken returns a session token expiring at exp
+func newToken(t *testing.T, exp time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1, "exp": exp.Unix()}).SignedString([]byte("test"))
+	require.NoError(t, err)
+	return token
+}
+
+// persistTo saves sessions in a temporary keyring for the test
+func persistTo(t *testing.T) {
+	PersistSessions(keyring.Open(t.TempDir()), "test")
+	t.Cleanup(func() {
+		persist.keyring = nil
+		Logout()
+	})
+}
+
+// TestResumeSaved ensures a saved session is resumed until locked
+func TestResumeSaved(t *testing.T) {
+	persistTo(t)
+	token := newToken(t, time.Now().Add(time.Hour))
+	client := &seedClient{token: token}
+
+	assert.ErrorIs(t, ResumeSaved(client), ErrNoSavedSession)
+
+	Resume("alice", token, nil)
+	saveSession()
+	clearSession()
+
+	require.NoError(t, ResumeSaved(client))
+	assert.Equal(t, token, Token())
+	assert.Equal(t, "alice", Username())
+	assert.Equal(t, DeriveKeyFromSeed(mockSeed), session.key, "The key should be cached after checking the token")
+
+	Lock()
+	assert.ErrorIs(t, ResumeSaved(client), ErrNoSavedSession, "Locking should forget the saved session")
+}
+
+// TestResumeSavedInvalid ensures expired and rejected sessions are forgotten
+func TestResumeSavedInvalid(t *testing.T) {
+	persistTo(t)
+	client := &seedClient{token: "other"}
+
+	Resume("alice", newToken(t, time.Now().Add(30*time.Second)), nil)
+	saveSession()
+	assert.ErrorIs(t, ResumeSaved(client), ErrNoSavedSession, "Sessions about to expire are not resumed")
+
+	Resume("alice", newToken(t, time.Now().Add(time.Hour)), nil)
+	saveSession()
+	assert.ErrorIs(t, ResumeSaved(client), ErrSessionRejected)
+	assert.Empty(t, Token())
+	assert.ErrorIs(t, ResumeSaved(client), ErrNoSavedSession, "Rejected sessions are forgotten")
+}
@@ -0,0 +1,28 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/archive"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// parseArchive decrypts a GophKeeper archive and converts its items.
+func parseArchive(r io.Reader, password string) (*Result, error) {
+	vault, err := archive.Open(r, password)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, item := range vault.Items {
+		dataType, ok := pb.DataType_value[item.Type]
+		if !ok {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: unknown data type %q", item.Metadata, item.Type))
+			continue
+		}
+		result.Entries = append(result.Entries, newEntry(pb.DataType(dataType), item.Metadata, item.Fields))
+	}
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/fields"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// Bitwarden item types as used in the unencrypted JSON export.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+)
+
+// bitwardenHiddenField is the type of Bitwarden custom fields masked in its UI.
+const bitwardenHiddenField = 1
+
+// bitwardenExport mirrors the parts of the Bitwarden JSON export we use.
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Items     []struct {
+		Type  int    `json:"type"`
+		Name  string `json:"name"`
+		Notes string `json:"notes"`
+		Login *struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Totp     string `json:"totp"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+		Card *struct {
+			CardholderName string `json:"cardholderName"`
+			Brand          string `json:"brand"`
+			Number         string `json:"number"`
+			ExpMonth       string `json:"expMonth"`
+			ExpYear        string `json:"expYear"`
+			Code           string `json:"code"`
+		} `json:"card"`
+		Fields []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			Type  int    `json:"type"`
+		} `json:"fields"`
+	} `json:"items"`
+}
+
+// parseBitwarden converts an unencrypted Bitwarden JSON export.
+func parseBitwarden(r io.Reader) (*Result, error) {
+	var export bitwardenExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("invalid Bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("encrypted Bitwarden exports are not supported, export as unencrypted JSON")
+	}
+
+	result := &Result{}
+	for _, item := range export.Items {
+		switch {
+		case item.Type == bitwardenLogin && item.Login != nil:
+			data := map[string]string{
+				"login":    item.Login.Username,
+				"password": item.Login.Password,
+				"notes":    item.Notes,
+				"totp":     item.Login.Totp,
+			}
+			if len(item.Login.URIs) > 0 {
+				data["url"] = item.Login.URIs[0].URI
+			}
+			var custom []fields.Field
+			for _, f := range item.Fields {
+				kind := fields.KindText
+				if f.Type == bitwardenHiddenField {
+					kind = fields.KindHidden
+				}
+				custom = append(custom, fields.Field{Name: f.Name, Kind: kind, Value: f.Value})
+			}
+			data[fields.DataKey] = customFieldsValue(custom)
+			result.Entries = append(result.Entries, newEntry(pb.DataType_CREDENTIALS, item.Name, data))
+		case item.Type == bitwardenCard && item.Card != nil:
+			data := map[string]string{
+				"card_number": item.Card.Number,
+				"cvv":         item.Card.Code,
+				"cardholder":  item.Card.CardholderName,
+				"notes":       item.Notes,
+			}
+			if item.Card.ExpMonth != "" || item.Card.ExpYear != "" {
+				data["expiration_date"] = expiration(item.Card.ExpMonth, item.Card.ExpYear)
+			}
+			result.Entries = append(result.Entries, newEntry(pb.DataType_CARD, item.Name, data))
+		case item.Type == bitwardenSecureNote:
+			result.Entries = append(result.Entries, newEntry(pb.DataType_TEXT, item.Name, map[string]string{"text": item.Notes}))
+		case item.Type == bitwardenIdentity:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: identity items are not supported", item.Name))
+		default:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: unknown Bitwarden item type %d", item.Name, item.Type))
+		}
+	}
+	return result, nil
+}
+
+// expiration formats a card expiry month and year as MM/YY.
+func expiration(month, year string) string {
+	month, year = strings.TrimSpace(month), strings.TrimSpace(year)
+	if len(month) == 1 {
+		month = "0" + month
+	}
+	if len(year) > 2 {
+		year = year[len(year)-2:]
+	}
+	return month + "/" + year
+}
@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// onePasswordMapping maps item fields to the columns of a 1Password CSV export.
+var onePasswordMapping = map[string]string{
+	"metadata": "Title",
+	"login":    "Username",
+	"password": "Password",
+	"url":      "URL",
+	"notes":    "Notes",
+}
+
+// lastPassSecureNoteURL marks secure notes in LastPass exports.
+const lastPassSecureNoteURL = "http://sn"
+
+// columnAliases lists alternative header names used by different exporters.
+var columnAliases = map[string][]string{
+	"url":   {"website", "login_uri"},
+	"login": {"login_username"},
+	"notes": {"extra", "note"},
+}
+
+// csvTable provides access to CSV rows by header name.
+type csvTable struct {
+	columns map[string]int
+	rows    [][]string
+}
+
+// readCSV reads a CSV file whose first row contains the column headers.
+func readCSV(r io.Reader) (*csvTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV export: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV export is empty")
+	}
+
+	table := &csvTable{columns: make(map[string]int), rows: records[1:]}
+	for i, name := range records[0] {
+		table.columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return table, nil
+}
+
+// value returns the cell of the named column, trying known aliases.
+func (t *csvTable) value(row []string, column string) string {
+	column = strings.ToLower(column)
+	names := append([]string{column}, columnAliases[column]...)
+	for _, name := range names {
+		if i, ok := t.columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+	}
+	return ""
+}
+
+// hasColumn reports whether the column or one of its aliases exists.
+func (t *csvTable) hasColumn(column string) bool {
+	column = strings.ToLower(column)
+	for _, name := range append([]string{column}, columnAliases[column]...) {
+		if _, ok := t.columns[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCSV converts a CSV export using a field -> column mapping.
+func parseCSV(r io.Reader, opts Options) (*Result, error) {
+	table, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	for field, column := range opts.Mapping {
+		if !table.hasColumn(column) {
+			return nil, fmt.Errorf("column %q mapped to %q not found in CSV header", column, field)
+		}
+	}
+
+	result := &Result{}
+	for n, row := range table.rows {
+		fields := make(map[string]string)
+		var metadata string
+		for field, column := range opts.Mapping {
+			if field == "metadata" {
+				metadata = table.value(row, column)
+				continue
+			}
+			fields[field] = table.value(row, column)
+		}
+
+		entry := newEntry(opts.DataType, metadata, fields)
+		if len(entry.Fields) == 0 {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("row %d: no mapped values", n+2))
+			continue
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+// parseLastPass converts a LastPass CSV export, turning secure notes into TEXT items.
+func parseLastPass(r io.Reader) (*Result, error) {
+	table, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range []string{"name", "username", "password", "url"} {
+		if !table.hasColumn(column) {
+			return nil, fmt.Errorf("not a LastPass export: missing %q column", column)
+		}
+	}
+
+	result := &Result{}
+	for _, row := range table.rows {
+		name := table.value(row, "name")
+		if table.value(row, "url") == lastPassSecureNoteURL {
+			result.Entries = append(result.Entries, newEntry(pb.DataType_TEXT, name, map[string]string{
+				"text":  table.value(row, "extra"),
+				"group": table.value(row, "grouping"),
+			}))
+			continue
+		}
+		result.Entries = append(result.Entries, newEntry(pb.DataType_CREDENTIALS, name, map[string]string{
+			"login":    table.value(row, "username"),
+			"password": table.value(row, "password"),
+			"url":      table.value(row, "url"),
+			"notes":    table.value(row, "extra"),
+			"totp":     table.value(row, "totp"),
+			"group":    table.value(row, "grouping"),
+		}))
+	}
+	return result, nil
+}
@@ -0,0 +1,187 @@
+// Package importer converts password manager exports into GophKeeper items.
+//
+// Supported sources are Bitwarden JSON, KeePass 2 XML, 1Password and LastPass
+// CSV exports, a generic CSV file with a user supplied column mapping, and
+// GophKeeper's own encrypted archives.
+// Parsed entries are kept in plaintext; encryption happens when they are
+// stored through the handlers package.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/fields"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// Format identifies the layout of an export file.
+type Format string
+
+// Supported import formats.
+const (
+	FormatBitwarden   Format = "bitwarden"
+	FormatKeePass     Format = "keepass"
+	FormatOnePassword Format = "1password"
+	FormatLastPass    Format = "lastpass"
+	FormatCSV         Format = "csv"
+	FormatArchive     Format = "gophkeeper"
+)
+
+// Formats lists every format accepted by Parse.
+var Formats = []Format{FormatBitwarden, FormatKeePass, FormatOnePassword, FormatLastPass, FormatCSV, FormatArchive}
+
+// Entry is a single plaintext item ready to be stored in the vault.
+type Entry struct {
+	DataType pb.DataType
+	Metadata string
+	Fields   map[string]string
+}
+
+// Key returns a stable identity of the entry used for duplicate detection.
+func (e Entry) Key() string {
+	fields, _ := json.Marshal(e.Fields) // map keys are marshalled in sorted order
+	return fmt.Sprintf("%d|%s|%s", e.DataType, e.Metadata, fields)
+}
+
+// Result holds the outcome of parsing an export file.
+type Result struct {
+	Entries []Entry  // Entries that can be stored
+	Skipped []string // Human readable reasons for records that were not converted
+}
+
+// Options configures parsing of the generic CSV and archive formats.
+type Options struct {
+	DataType pb.DataType       // Type assigned to every imported row
+	Mapping  map[string]string // Item field name -> CSV column header
+	Password string            // Password of a GophKeeper archive
+}
+
+// Parse reads an export in the given format.
+func Parse(format Format, r io.Reader, opts Options) (*Result, error) {
+	switch format {
+	case FormatBitwarden:
+		return parseBitwarden(r)
+	case FormatKeePass:
+		return parseKeePass(r)
+	case FormatOnePassword:
+		return parseCSV(r, Options{DataType: pb.DataType_CREDENTIALS, Mapping: onePasswordMapping})
+	case FormatLastPass:
+		return parseLastPass(r)
+	case FormatCSV:
+		if len(opts.Mapping) == 0 {
+			return nil, fmt.Errorf("generic CSV import requires a column mapping")
+		}
+		return parseCSV(r, opts)
+	case FormatArchive:
+		return parseArchive(r, opts.Password)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// ParseMapping parses a column mapping such as "metadata=Name,login=User".
+func ParseMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(field) == "" || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected field=column", pair)
+		}
+		mapping[strings.TrimSpace(field)] = strings.TrimSpace(column)
+	}
+	return mapping, nil
+}
+
+// Dedupe drops entries that already exist in the vault or appear earlier in
+// the same import. It returns the entries to store and the dropped duplicates.
+func Dedupe(entries, existing []Entry) (unique, duplicates []Entry) {
+	seen := make(map[string]bool, len(existing)+len(entries))
+	for _, e := range existing {
+		seen[e.Key()] = true
+	}
+	for _, e := range entries {
+		key := e.Key()
+		if seen[key] {
+			duplicates = append(duplicates, e)
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, e)
+	}
+	return unique, duplicates
+}
+
+// Summary returns a one-line description of the entry without secret values.
+func (e Entry) Summary() string {
+	var details []string
+	for _, name := range []string{"login", "url"} {
+		if v := e.Fields[name]; v != "" {
+			details = append(details, fmt.Sprintf("%s: %s", name, v))
+		}
+	}
+	if number := e.Fields["card_number"]; len(number) >= 4 {
+		details = append(details, "card: **** "+number[len(number)-4:])
+	}
+
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	details = append(details, "fields: "+strings.Join(names, ", "))
+
+	return fmt.Sprintf("[%s] %s (%s)", e.DataType, e.Metadata, strings.Join(details, "; "))
+}
+
+// newEntry builds an entry, dropping empty field values.
+func newEntry(dataType pb.DataType, metadata string, fields map[string]string) Entry {
+	entry := Entry{DataType: dataType, Metadata: strings.TrimSpace(metadata), Fields: make(map[string]string)}
+	for name, value := range fields {
+		if value != "" {
+			entry.Fields[name] = value
+		}
+	}
+	return entry
+}
+
+// customFieldsValue encodes the extra fields of an imported item as custom
+// fields, so hidden ones stay masked and out of the search index. Fields
+// without a name are dropped and repeated names are numbered, as custom
+// field names must be unique.
+func customFieldsValue(custom []fields.Field) string {
+	var unique []fields.Field
+	seen := make(map[string]bool)
+	for _, f := range custom {
+		name := strings.TrimSpace(f.Name)
+		if name == "" {
+			continue
+		}
+		for n := 2; seen[name]; n++ {
+			name = fmt.Sprintf("%s (%d)", strings.TrimSpace(f.Name), n)
+		}
+		seen[name] = true
+		unique = append(unique, fields.Field{Name: name, Kind: f.Kind, Value: f.Value})
+	}
+	if len(unique) == 0 {
+		return ""
+	}
+	encoded, _ := fields.Encode(unique) // Names are unique and text and hidden values always valid
+	return encoded
+}
+
+// EntryFromItem converts a decrypted vault item into an entry.
+func EntryFromItem(item *pb.DataItem) (Entry, error) {
+	fields := make(map[string]string)
+	if err := json.Unmarshal(item.Data, &fields); err != nil {
+		return Entry{}, fmt.Errorf("failed to decode item %q: %w", item.Metadata, err)
+	}
+	return newEntry(item.DataType, item.Metadata, fields), nil
+}
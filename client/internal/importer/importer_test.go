@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/archive"
+	"github.com/golangTroshin/gophkeeper/client/internal/fields"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bitwardenExportJSON = `{
+  "encrypted": false,
+  "items": [
+    {"type": 1, "name": "GitHub", "notes": "work account",
+     "login": {"username": "alice", "password": "s3cret", "totp": "JBSWY3DPEHPK3PXP", "uris": [{"uri": "https://github.com"}]},
+     "fields": [{"name": "pin", "value": "4321", "type": 1}, {"name": "team", "value": "core", "type": 0}, {"name": "team", "value": "ops", "type": 0}]},
+    {"type": 2, "name": "Recovery codes", "notes": "1111 2222"},
+    {"type": 3, "name": "Visa", "card": {"cardholderName": "Alice", "number": "4111111111111111", "expMonth": "3", "expYear": "2027", "code": "123"}},
+    {"type": 4, "name": "Passport"}
+  ]
+}`
+
+const keepassExportXML = `<?xml version="1.0" encoding="utf-8"?>
+<KeePassFile>
+  <Root>
+    <Group>
+      <Name>Root</Name>
+      <Entry>
+        <String><Key>Title</Key><Value>Mail</Value></String>
+        <String><Key>UserName</Key><Value>bob</Value></String>
+        <String><Key>Password</Key><Value ProtectInMemory="True">pa55</Value></String>
+        <String><Key>URL</Key><Value>https://mail.example.com</Value></String>
+        <String><Key>Recovery key</Key><Value ProtectInMemory="True">r3c0very</Value></String>
+      </Entry>
+      <Group>
+        <Name>Notes</Name>
+        <Entry>
+          <String><Key>Title</Key><Value>Wi-Fi</Value></String>
+          <String><Key>Notes</Key><Value>guest/guest</Value></String>
+        </Entry>
+      </Group>
+      <Group>
+        <Name>Recycle Bin</Name>
+        <Entry>
+          <String><Key>Title</Key><Value>Old</Value></String>
+          <String><Key>Password</Key><Value>old</Value></String>
+        </Entry>
+      </Group>
+    </Group>
+  </Root>
+</KeePassFile>`
+
+// TestParseBitwarden ensures logins, notes and cards are mapped to vault types
+func TestParseBitwarden(t *testing.T) {
+	result, err := Parse(FormatBitwarden, strings.NewReader(bitwardenExportJSON), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 3)
+	assert.Len(t, result.Skipped, 1, "Identity items should be skipped")
+
+	login := result.Entries[0]
+	assert.Equal(t, pb.DataType_CREDENTIALS, login.DataType)
+	assert.Equal(t, "GitHub", login.Metadata)
+	assert.Equal(t, "alice", login.Fields["login"])
+	assert.Equal(t, "s3cret", login.Fields["password"])
+	assert.Equal(t, "https://github.com", login.Fields["url"])
+	assert.True(t, fields.IsSecret("totp"), "TOTP seeds should be masked")
+	custom, err := fields.FromData(login.Fields)
+	require.NoError(t, err)
+	assert.Equal(t, []fields.Field{
+		{Name: "pin", Kind: fields.KindHidden, Value: "4321"},
+		{Name: "team", Kind: fields.KindText, Value: "core"},
+		{Name: "team (2)", Kind: fields.KindText, Value: "ops"},
+	}, custom, "Hidden fields should stay hidden and names unique")
+
+	assert.Equal(t, pb.DataType_TEXT, result.Entries[1].DataType)
+	assert.Equal(t, "1111 2222", result.Entries[1].Fields["text"])
+
+	card := result.Entries[2]
+	assert.Equal(t, pb.DataType_CARD, card.DataType)
+	assert.Equal(t, "03/27", card.Fields["expiration_date"])
+	assert.Equal(t, "123", card.Fields["cvv"])
+}
+
+// TestParseBitwardenEncrypted ensures encrypted exports are rejected
+func TestParseBitwardenEncrypted(t *testing.T) {
+	_, err := Parse(FormatBitwarden, strings.NewReader(`{"encrypted": true, "items": []}`), Options{})
+	assert.Error(t, err, "Encrypted exports should be rejected")
+}
+
+// TestParseKeePass ensures nested groups are walked and the recycle bin is ignored
+func TestParseKeePass(t *testing.T) {
+	result, err := Parse(FormatKeePass, strings.NewReader(keepassExportXML), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 2)
+
+	assert.Equal(t, pb.DataType_CREDENTIALS, result.Entries[0].DataType)
+	assert.Equal(t, "bob", result.Entries[0].Fields["login"])
+	assert.Equal(t, "pa55", result.Entries[0].Fields["password"])
+	custom, err := fields.FromData(result.Entries[0].Fields)
+	require.NoError(t, err)
+	assert.Equal(t, []fields.Field{{Name: "Recovery key", Kind: fields.KindHidden, Value: "r3c0very"}}, custom, "Protected strings should be hidden")
+
+	assert.Equal(t, pb.DataType_TEXT, result.Entries[1].DataType)
+	assert.Equal(t, "Root/Notes", result.Entries[1].Fields["group"])
+}
+
+// TestParseLastPass ensures secure notes become TEXT items
+func TestParseLastPass(t *testing.T) {
+	export := "url,username,password,totp,extra,name,grouping,fav\n" +
+		"https://example.com,carol,pw,,,Example,Web,0\n" +
+		"http://sn,,,,my note,Note,,0\n"
+
+	result, err := Parse(FormatLastPass, strings.NewReader(export), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 2)
+	assert.Equal(t, "carol", result.Entries[0].Fields["login"])
+	assert.Equal(t, pb.DataType_TEXT, result.Entries[1].DataType)
+	assert.Equal(t, "my note", result.Entries[1].Fields["text"])
+}
+
+// TestParseOnePassword ensures the 1Password column layout is recognised
+func TestParseOnePassword(t *testing.T) {
+	export := "Title,Url,Username,Password,Notes\nBank,https://bank.example,dave,hunter2,\n"
+
+	result, err := Parse(FormatOnePassword, strings.NewReader(export), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 1)
+	assert.Equal(t, "Bank", result.Entries[0].Metadata)
+	assert.Equal(t, "hunter2", result.Entries[0].Fields["password"])
+}
+
+// TestParseGenericCSV ensures user supplied mappings are applied
+func TestParseGenericCSV(t *testing.T) {
+	mapping, err := ParseMapping("metadata=Name, text=Body")
+	require.NoError(t, err)
+
+	export := "Name,Body\nGreeting,hello\nEmpty,\n"
+	result, err := Parse(FormatCSV, strings.NewReader(export), Options{DataType: pb.DataType_TEXT, Mapping: mapping})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 1)
+	assert.Equal(t, "hello", result.Entries[0].Fields["text"])
+	assert.Len(t, result.Skipped, 1, "Rows without values should be skipped")
+
+	_, err = Parse(FormatCSV, strings.NewReader(export), Options{Mapping: map[string]string{"login": "Missing"}})
+	assert.Error(t, err, "Unknown columns should be reported")
+
+	_, err = ParseMapping("metadata")
+	assert.Error(t, err, "Mappings without a column should be rejected")
+}
+
+// TestDedupe ensures duplicates against the vault and within the import are dropped
+func TestDedupe(t *testing.T) {
+	a := newEntry(pb.DataType_CREDENTIALS, "A", map[string]string{"login": "a", "password": "1"})
+	b := newEntry(pb.DataType_CREDENTIALS, "B", map[string]string{"login": "b", "password": "2"})
+	existing := []Entry{newEntry(pb.DataType_CREDENTIALS, "A", map[string]string{"login": "a", "password": "1", "notes": ""})}
+
+	unique, duplicates := Dedupe([]Entry{a, b, b}, existing)
+	assert.Equal(t, []Entry{b}, unique)
+	assert.Len(t, duplicates, 2)
+}
+
+// TestEntryFromItem ensures decrypted items are converted for comparison
+func TestEntryFromItem(t *testing.T) {
+	entry, err := EntryFromItem(&pb.DataItem{DataType: pb.DataType_TEXT, Metadata: "note", Data: []byte(`{"text":"hi"}`)})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", entry.Fields["text"])
+
+	_, err = EntryFromItem(&pb.DataItem{Data: []byte("not json")})
+	assert.Error(t, err)
+}
+
+// TestParseArchive ensures GophKeeper archives are imported with their original types
+func TestParseArchive(t *testing.T) {
+	var buf bytes.Buffer
+	vault := &archive.Vault{Items: []archive.Item{
+		{Type: "CARD", Metadata: "Visa", Fields: map[string]string{"card_number": "4111111111111111"}},
+		{Type: "UNKNOWN", Metadata: "Future item"},
+	}}
+	require.NoError(t, archive.Seal(&buf, vault, "pass"))
+
+	result, err := Parse(FormatArchive, &buf, Options{Password: "pass"})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 1)
+	assert.Equal(t, pb.DataType_CARD, result.Entries[0].DataType)
+	assert.Len(t, result.Skipped, 1)
+}
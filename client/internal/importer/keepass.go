@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/fields"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// keepassGroup mirrors a <Group> element of a KeePass 2 XML export.
+type keepassGroup struct {
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+// keepassEntry mirrors an <Entry> element; values are stored as key/value strings.
+type keepassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Text      string `xml:",chardata"`
+			Protected bool   `xml:"ProtectInMemory,attr"` // Masked in KeePass
+		} `xml:"Value"`
+	} `xml:"String"`
+}
+
+// keepassRecycleBin is the default name of the KeePass trash group.
+const keepassRecycleBin = "Recycle Bin"
+
+// parseKeePass converts an unencrypted KeePass 2 XML export.
+func parseKeePass(r io.Reader) (*Result, error) {
+	var file struct {
+		XMLName xml.Name       `xml:"KeePassFile"`
+		Groups  []keepassGroup `xml:"Root>Group"`
+	}
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid KeePass XML export: %w", err)
+	}
+
+	result := &Result{}
+	for _, group := range file.Groups {
+		walkKeePassGroup(group, "", result)
+	}
+	return result, nil
+}
+
+// walkKeePassGroup converts the entries of a group and its subgroups.
+// Group names are kept as a path in the "group" field.
+func walkKeePassGroup(group keepassGroup, parent string, result *Result) {
+	if group.Name == keepassRecycleBin {
+		return
+	}
+	path := group.Name
+	if parent != "" {
+		path = parent + "/" + group.Name
+	}
+
+	for _, e := range group.Entries {
+		values := make(map[string]string)
+		for _, s := range e.Strings {
+			values[s.Key] = s.Value.Text
+		}
+
+		title := values["Title"]
+		if values["UserName"] == "" && values["Password"] == "" {
+			if values["Notes"] == "" {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s: entry has no credentials or notes", title))
+				continue
+			}
+			result.Entries = append(result.Entries, newEntry(pb.DataType_TEXT, title, map[string]string{
+				"text":  values["Notes"],
+				"group": path,
+			}))
+			continue
+		}
+
+		data := map[string]string{
+			"login":    values["UserName"],
+			"password": values["Password"],
+			"url":      values["URL"],
+			"notes":    values["Notes"],
+			"group":    path,
+		}
+		var custom []fields.Field
+		for _, s := range e.Strings {
+			switch s.Key {
+			case "Title", "UserName", "Password", "URL", "Notes":
+			default:
+				kind := fields.KindText
+				if s.Value.Protected {
+					kind = fields.KindHidden
+				}
+				custom = append(custom, fields.Field{Name: s.Key, Kind: kind, Value: s.Value.Text})
+			}
+		}
+		data[fields.DataKey] = customFieldsValue(custom)
+		result.Entries = append(result.Entries, newEntry(pb.DataType_CREDENTIALS, title, data))
+	}
+
+	for _, sub := range group.Groups {
+		walkKeePassGroup(sub, path, result)
+	}
+}
@@ -0,0 +1,212 @@
+// Package keyring stores small secrets, such as login sessions, in encrypted
+// files readable only by the current user.
+//
+// It emulates an OS keyring on every platform: entries are encrypted with
+// AES-GCM under a random key kept in a separate file of the keyring
+// directory, and each entry is bound to its name so files cannot be swapped.
+// Like any file-based keyring it protects copies of single entries, for
+// example in backups, but not against someone who can read the whole
+// directory as the user.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+)
+
+// DirEnv overrides the keyring directory.
+const DirEnv = "GOPHKEEPER_KEYRING_DIR"
+
+// ErrNotFound is returned for missing entries.
+var ErrNotFound = errors.New("keyring entry not found")
+
+// validName matches entry names, which are used as file names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// keySize is the AES-256 key size.
+const keySize = 32
+
+// Keyring is a directory of encrypted entries.
+type Keyring struct {
+	dir string
+}
+
+// Open returns the keyring stored in dir. The directory is created on the
+// first Set.
+func Open(dir string) *Keyring {
+	return &Keyring{dir: dir}
+}
+
+// DefaultDir returns DirEnv, or gophkeeper in the user's state directory
+// ($XDG_STATE_HOME or ~/.local/state).
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "gophkeeper"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "gophkeeper"), nil
+}
+
+// Set encrypts and stores a secret under name, replacing an existing entry.
+func (k *Keyring) Set(name string, secret []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(k.dir, 0700); err != nil {
+		return err
+	}
+	key, err := k.key(true)
+	if err != nil {
+		return err
+	}
+	defer wipe(key)
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	return writePrivate(k.entryPath(name), aead.Seal(nonce, nonce, secret, []byte(name)))
+}
+
+// Get decrypts the secret stored under name.
+func (k *Keyring) Get(name string) ([]byte, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	data, err := readPrivate(k.entryPath(name))
+	if err != nil {
+		return nil, err
+	}
+	key, err := k.key(false)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(key)
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("keyring entry %q is corrupted", name)
+	}
+	secret, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("keyring entry %q cannot be decrypted", name)
+	}
+	return secret, nil
+}
+
+// Delete removes the entry stored under name. Missing entries are ignored.
+func (k *Keyring) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(k.entryPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// entryPath returns the file of an entry.
+func (k *Keyring) entryPath(name string) string {
+	return filepath.Join(k.dir, name+".enc")
+}
+
+// key reads the keyring key, generating it if create is set.
+func (k *Keyring) key(create bool) ([]byte, error) {
+	path := filepath.Join(k.dir, "keyring.key")
+	key, err := readPrivate(path)
+	if errors.Is(err, ErrNotFound) && create {
+		key = make([]byte, keySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		return key, writePrivate(path, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("%s: invalid key size", path)
+	}
+	return key, nil
+}
+
+// newAEAD creates the AES-GCM cipher for key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// checkName rejects names that are not safe file names.
+func checkName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid keyring entry name %q", name)
+	}
+	return nil
+}
+
+// writePrivate atomically replaces a file with one only the user can read.
+func writePrivate(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil && runtime.GOOS != "windows" {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readPrivate reads a file, refusing files other users can access.
+func readPrivate(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s: permissions %v are too open, expected 0600", path, info.Mode().Perm())
+	}
+	return os.ReadFile(path)
+}
+
+// wipe overwrites key material that is no longer needed.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
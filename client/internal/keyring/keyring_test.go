@@ -0,0 +1,60 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeyring ensures entries are stored encrypted and can be replaced and deleted
+func TestKeyring(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keyring")
+	k := Open(dir)
+
+	_, err := k.Get("session-work")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, k.Set("session-work", []byte("token-1")))
+	require.NoError(t, k.Set("session-work", []byte("token-2")))
+	secret, err := k.Get("session-work")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("token-2"), secret)
+
+	raw, err := os.ReadFile(filepath.Join(dir, "session-work.enc"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "token-2", "Entries should be encrypted")
+
+	for _, file := range []string{"session-work.enc", "keyring.key"} {
+		info, err := os.Stat(filepath.Join(dir, file))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), file)
+	}
+
+	require.NoError(t, k.Delete("session-work"))
+	require.NoError(t, k.Delete("session-work"), "Deleting twice is not an error")
+	_, err = k.Get("session-work")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// TestKeyringTampering ensures swapped entries and open permissions are rejected
+func TestKeyringTampering(t *testing.T) {
+	dir := t.TempDir()
+	k := Open(dir)
+	require.NoError(t, k.Set("session-home", []byte("home token")))
+	require.NoError(t, k.Set("session-work", []byte("work token")))
+
+	home, err := os.ReadFile(filepath.Join(dir, "session-home.enc"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "session-work.enc"), home, 0600))
+	_, err = k.Get("session-work")
+	assert.ErrorContains(t, err, "cannot be decrypted", "Entries are bound to their names")
+
+	require.NoError(t, os.Chmod(filepath.Join(dir, "session-home.enc"), 0644))
+	_, err = k.Get("session-home")
+	assert.ErrorContains(t, err, "too open")
+
+	assert.Error(t, k.Set("../escape", []byte("x")))
+}
@@ -0,0 +1,149 @@
+// Package markdown renders a subset of Markdown as tview color tags for
+// displaying secure notes in the terminal.
+//
+// Supported are ATX headings, emphasis, inline code, fenced code blocks,
+// links, block quotes, lists and horizontal rules. Everything else is shown
+// as plain text, with tview tags in the source escaped.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	ruleRe    = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	bulletRe  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	taskRe    = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	quoteRe   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+)
+
+// Render converts Markdown source to text with tview color tags.
+func Render(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+
+	inFence := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, "[aqua]  "+tview.Escape(line)+"[-]")
+			continue
+		}
+		out = append(out, renderLine(line))
+	}
+	return strings.Join(out, "\n")
+}
+
+// renderLine renders a single line outside of code blocks.
+func renderLine(line string) string {
+	if m := headingRe.FindStringSubmatch(line); m != nil {
+		text := Inline(m[2])
+		if len(m[1]) == 1 {
+			return "[yellow::bu]" + text + "[-::-]"
+		}
+		return "[yellow::b]" + text + "[-::-]"
+	}
+	if ruleRe.MatchString(line) {
+		return "[gray]" + strings.Repeat("─", 40) + "[-]"
+	}
+	if m := quoteRe.FindStringSubmatch(line); m != nil {
+		return "[gray]│[-] [::i]" + Inline(m[1]) + "[::I]"
+	}
+	if m := bulletRe.FindStringSubmatch(line); m != nil {
+		if t := taskRe.FindStringSubmatch(m[2]); t != nil {
+			box := "☐"
+			if t[1] != " " {
+				box = "☑"
+			}
+			return m[1] + box + " " + Inline(t[2])
+		}
+		return m[1] + "• " + Inline(m[2])
+	}
+	if m := orderedRe.FindStringSubmatch(line); m != nil {
+		return m[1] + m[2] + " " + Inline(m[3])
+	}
+	return Inline(line)
+}
+
+// Inline renders emphasis, code spans and links within a line.
+func Inline(s string) string {
+	var out, literal strings.Builder
+	flush := func() {
+		out.WriteString(tview.Escape(literal.String()))
+		literal.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				flush()
+				out.WriteString("[aqua]" + tview.Escape(s[i+1:i+1+end]) + "[-]")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**") || strings.HasPrefix(s[i:], "__"):
+			marker := s[i : i+2]
+			if end := strings.Index(s[i+2:], marker); end > 0 {
+				flush()
+				out.WriteString("[::b]" + Inline(s[i+2:i+2+end]) + "[::B]")
+				i += end + 4
+				continue
+			}
+			literal.WriteString(marker)
+			i += 2
+			continue
+		case s[i] == '*':
+			if end := strings.IndexByte(s[i+1:], '*'); end > 0 && s[i+1] != ' ' && s[i+end] != ' ' {
+				flush()
+				out.WriteString("[::i]" + Inline(s[i+1:i+1+end]) + "[::I]")
+				i += end + 2
+				continue
+			}
+		case s[i] == '~' && strings.HasPrefix(s[i:], "~~"):
+			if end := strings.Index(s[i+2:], "~~"); end > 0 {
+				flush()
+				out.WriteString("[::s]" + Inline(s[i+2:i+2+end]) + "[::S]")
+				i += end + 4
+				continue
+			}
+		case s[i] == '[':
+			if text, url, n, ok := link(s[i:]); ok {
+				flush()
+				out.WriteString("[::u]" + tview.Escape(text) + "[::U] [gray](" + tview.Escape(url) + ")[-]")
+				i += n
+				continue
+			}
+		}
+		literal.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return out.String()
+}
+
+// link parses a [text](url) link at the start of s and returns its length.
+func link(s string) (text, url string, n int, ok bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 1 {
+		return "", "", 0, false
+	}
+	closeURL := strings.IndexByte(s[closeText+2:], ')')
+	if closeURL < 0 {
+		return "", "", 0, false
+	}
+	text = s[1:closeText]
+	url = s[closeText+2 : closeText+2+closeURL]
+	if strings.ContainsAny(text, "[]") || strings.ContainsAny(url, " \t") {
+		return "", "", 0, false
+	}
+	return text, url, closeText + 3 + closeURL, true
+}
@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestInline ensures emphasis, code and links are converted to style tags
+func TestInline(t *testing.T) {
+	tests := map[string]string{
+		"plain text":                   "plain text",
+		"**bold** and *italic*":        "[::b]bold[::B] and [::i]italic[::I]",
+		"__bold__ ~~gone~~":            "[::b]bold[::B] [::s]gone[::S]",
+		"run `rm -rf [tmp]`":           "run [aqua]rm -rf [tmp[][-]",
+		"see [docs](https://x.io/a)":   "see [::u]docs[::U] [gray](https://x.io/a)[-]",
+		"**nested *italic* bold**":     "[::b]nested [::i]italic[::I] bold[::B]",
+		"unclosed **bold and * star":   "unclosed **bold and * star",
+		"tags [red]stay[-] plain text": "tags [red[]stay[-[] plain text",
+		"2 * 3 * 4 = 24":               "2 * 3 * 4 = 24",
+	}
+	for src, want := range tests {
+		assert.Equal(t, want, Inline(src), src)
+	}
+}
+
+// TestRender ensures block elements are rendered line by line
+func TestRender(t *testing.T) {
+	src := "# Title\n## Section\n> quoted\n- item\n  * sub\n- [x] done\n1. first\n---\n```\n**not bold**\n```\ntext"
+	want := []string{
+		"[yellow::bu]Title[-::-]",
+		"[yellow::b]Section[-::-]",
+		"[gray]│[-] [::i]quoted[::I]",
+		"• item",
+		"  • sub",
+		"☑ done",
+		"1. first",
+		"[gray]────────────────────────────────────────[-]",
+		"[aqua]  **not bold**[-]",
+		"text",
+	}
+	got := Render(src)
+	for _, line := range want {
+		assert.Contains(t, got, line)
+	}
+	assert.NotContains(t, got, "```")
+}
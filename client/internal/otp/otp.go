@@ -0,0 +1,189 @@
+// Package otp parses otpauth:// URIs and computes time-based one-time
+// passwords as defined by RFC 6238 (TOTP), built on RFC 4226 (HOTP).
+//
+// Counter-based HOTP keys are out of scope and rejected by Parse: every code
+// advances the counter, which would have to be written back to the vault item
+// after each use, and items cannot be updated in place. Key.HOTP computes the
+// RFC 4226 code that TOTP builds on.
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults from the Key URI format used by Google Authenticator and others.
+const (
+	defaultDigits    = 6
+	defaultPeriod    = 30 * time.Second
+	defaultAlgorithm = "SHA1"
+)
+
+// Key holds the parameters of an OTP generator.
+type Key struct {
+	Issuer    string        // Service the key belongs to
+	Account   string        // Account name, usually the login
+	Secret    []byte        // Shared secret
+	Algorithm string        // SHA1, SHA256 or SHA512
+	Digits    int           // Code length, 6 to 8
+	Period    time.Duration // Time step
+}
+
+// Parse parses an otpauth URI such as
+// otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example.
+func Parse(uri string) (*Key, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("invalid otpauth URI: scheme must be otpauth")
+	}
+
+	switch strings.ToLower(u.Host) {
+	case "totp":
+	case "hotp":
+		return nil, fmt.Errorf("counter-based HOTP keys are not supported, use a TOTP key")
+	default:
+		return nil, fmt.Errorf("unsupported OTP type %q", u.Host)
+	}
+
+	key := &Key{
+		Algorithm: defaultAlgorithm,
+		Digits:    defaultDigits,
+		Period:    defaultPeriod,
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		key.Issuer, key.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		key.Account = label
+	}
+
+	q := u.Query()
+	if issuer := q.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+
+	key.Secret, err = decodeSecret(q.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+
+	if alg := q.Get("algorithm"); alg != "" {
+		key.Algorithm = strings.ToUpper(alg)
+		if _, err := key.hash(); err != nil {
+			return nil, err
+		}
+	}
+	if digits := q.Get("digits"); digits != "" {
+		key.Digits, err = strconv.Atoi(digits)
+		if err != nil || key.Digits < 6 || key.Digits > 8 {
+			return nil, fmt.Errorf("invalid digits %q", digits)
+		}
+	}
+	if period := q.Get("period"); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid period %q", period)
+		}
+		key.Period = time.Duration(seconds) * time.Second
+	}
+	return key, nil
+}
+
+// Code returns the TOTP code for time t.
+func (k *Key) Code(t time.Time) (string, error) {
+	return k.HOTP(uint64(t.Unix()) / uint64(k.Period/time.Second))
+}
+
+// Remaining returns how long the TOTP code for time t stays valid.
+func (k *Key) Remaining(t time.Time) time.Duration {
+	period := int64(k.Period / time.Second)
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
+
+// HOTP computes the RFC 4226 code for a counter value.
+func (k *Key) HOTP(counter uint64) (string, error) {
+	newHash, err := k.hash()
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(newHash, k.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, value%mod), nil
+}
+
+// Label returns a human readable name of the key.
+func (k *Key) Label() string {
+	switch {
+	case k.Issuer != "" && k.Account != "":
+		return k.Issuer + " (" + k.Account + ")"
+	case k.Issuer != "":
+		return k.Issuer
+	default:
+		return k.Account
+	}
+}
+
+// hash returns the HMAC hash function for the key's algorithm.
+func (k *Key) hash() (func() hash.Hash, error) {
+	switch k.Algorithm {
+	case "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", k.Algorithm)
+	}
+}
+
+// decodeSecret decodes a base32 secret, tolerating lowercase, spaces and missing padding.
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	if secret == "" {
+		return nil, fmt.Errorf("otpauth URI has no secret")
+	}
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base32 secret: %w", err)
+	}
+	return decoded, nil
+}
+
+// ParseItem parses the decrypted data of an OTP vault item, a JSON object
+// holding the otpauth URI under the "uri" key.
+func ParseItem(data []byte) (*Key, error) {
+	var fields map[string]string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid OTP item: %w", err)
+	}
+	return Parse(fields["uri"])
+}
@@ -0,0 +1,92 @@
+package otp
+
+import (
+	"encoding/base32"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC test secrets: "12345678901234567890" repeated to the HMAC block size.
+var (
+	secretSHA1   = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	secretSHA256 = base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	secretSHA512 = base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+)
+
+// TestHOTPVectors checks the RFC 4226 appendix D test values
+func TestHOTPVectors(t *testing.T) {
+	key := &Key{Secret: []byte("12345678901234567890"), Algorithm: "SHA1", Digits: 6}
+
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, want := range expected {
+		code, err := key.HOTP(uint64(counter))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "HOTP counter %d", counter)
+	}
+}
+
+// TestTOTPVectors checks the RFC 6238 appendix B test values
+func TestTOTPVectors(t *testing.T) {
+	vectors := []struct {
+		unix   int64
+		sha1   string
+		sha256 string
+		sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	}
+
+	for _, v := range vectors {
+		for alg, want := range map[string]string{"SHA1": v.sha1, "SHA256": v.sha256, "SHA512": v.sha512} {
+			secret := map[string]string{"SHA1": secretSHA1, "SHA256": secretSHA256, "SHA512": secretSHA512}[alg]
+			key, err := Parse(fmt.Sprintf("otpauth://totp/Test?digits=8&algorithm=%s&secret=%s", alg, secret))
+			require.NoError(t, err)
+
+			code, err := key.Code(time.Unix(v.unix, 0))
+			require.NoError(t, err)
+			assert.Equal(t, want, code, "TOTP %s at %d", alg, v.unix)
+		}
+	}
+}
+
+// TestParse ensures labels, issuers and defaults are read from the URI
+func TestParse(t *testing.T) {
+	key, err := Parse("otpauth://totp/ACME%20Co:john@example.com?secret=jbsw y3dp ehpk 3pxp&issuer=ACME%20Co")
+	require.NoError(t, err)
+	assert.Equal(t, "ACME Co", key.Issuer)
+	assert.Equal(t, "john@example.com", key.Account)
+	assert.Equal(t, 6, key.Digits)
+	assert.Equal(t, 30*time.Second, key.Period)
+	assert.Equal(t, "ACME Co (john@example.com)", key.Label())
+}
+
+// TestParseInvalid ensures malformed URIs are rejected
+func TestParseInvalid(t *testing.T) {
+	for _, uri := range []string{
+		"https://example.com",
+		"otpauth://motp/Test?secret=" + secretSHA1,
+		"otpauth://totp/Test",
+		"otpauth://totp/Test?secret=!!!",
+		"otpauth://totp/Test?digits=12&secret=" + secretSHA1,
+		"otpauth://totp/Test?algorithm=MD5&secret=" + secretSHA1,
+		"otpauth://hotp/Test?counter=0&secret=" + secretSHA1,
+	} {
+		_, err := Parse(uri)
+		assert.Error(t, err, uri)
+	}
+}
+
+// TestRemaining ensures the countdown reaches the next period boundary
+func TestRemaining(t *testing.T) {
+	key, err := Parse("otpauth://totp/Test?period=60&secret=" + secretSHA1)
+	require.NoError(t, err)
+	assert.Equal(t, 60*time.Second, key.Remaining(time.Unix(120, 0)))
+	assert.Equal(t, 15*time.Second, key.Remaining(time.Unix(165, 0)))
+}
@@ -0,0 +1,309 @@
+// Package search implements fuzzy full-text search over decrypted vault items.
+//
+// The index only contains item descriptions and fields that are not secret:
+// passwords, card numbers, security codes, private keys, OTP secrets and
+// hidden custom fields are never indexed, so search results can be shown
+// without revealing them.
+package search
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/card"
+	"github.com/golangTroshin/gophkeeper/client/internal/fields"
+	"github.com/golangTroshin/gophkeeper/client/internal/otp"
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+)
+
+// internalKeys are item data keys that are not indexed besides secrets.
+var internalKeys = map[string]bool{
+	"key_file":           true,
+	"file_data":          true,
+	fields.DataKey:       true,
+	fields.DefinitionKey: true,
+	fields.TagsKey:       true,
+	fields.TemplateKey:   true,
+}
+
+// Field weights, so matches in the description rank first.
+const (
+	metadataWeight = 3
+	fieldWeight    = 1
+)
+
+// document is an indexed item.
+type document struct {
+	item   *pb.DataItem
+	tags   []string
+	fields []field
+}
+
+// field is a searchable value of an item.
+type field struct {
+	name   string
+	value  string // Lower case
+	weight int
+}
+
+// Index is an in-memory search index.
+type Index struct {
+	docs []document
+}
+
+// Result is a matching item.
+type Result struct {
+	Item  *pb.DataItem
+	Tags  []string
+	Field string // Name of the best matching field
+	Score int
+}
+
+// New indexes decrypted items.
+func New(items []*pb.DataItem) *Index {
+	idx := &Index{docs: make([]document, 0, len(items))}
+	for _, item := range items {
+		if item.DataType == pb.DataType_TEMPLATE {
+			continue
+		}
+		idx.docs = append(idx.docs, newDocument(item))
+	}
+	return idx
+}
+
+// Len returns the number of indexed items.
+func (idx *Index) Len() int {
+	return len(idx.docs)
+}
+
+// newDocument extracts the searchable fields of an item.
+func newDocument(item *pb.DataItem) document {
+	doc := document{item: item}
+	doc.add("description", item.Metadata, metadataWeight)
+
+	var data map[string]string
+	if err := json.Unmarshal(item.Data, &data); err != nil {
+		return doc
+	}
+	doc.tags = fields.ParseTags(data[fields.TagsKey])
+	for _, tag := range doc.tags {
+		doc.add("tag", tag, fieldWeight)
+	}
+
+	names := make([]string, 0, len(data))
+	for name := range data {
+		if !fields.IsSecret(name) && !internalKeys[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.add(name, data[name], fieldWeight)
+	}
+
+	if number := card.Normalize(data[card.FieldNumber]); len(number) >= 4 {
+		doc.add("card", number[len(number)-4:], fieldWeight)
+	}
+	if key, err := otp.Parse(data["uri"]); err == nil {
+		doc.add("issuer", key.Issuer, fieldWeight)
+		doc.add("account", key.Account, fieldWeight)
+	}
+	if custom, err := fields.FromData(data); err == nil {
+		for _, f := range custom {
+			if f.Kind != fields.KindHidden {
+				doc.add(f.Name, f.Value, fieldWeight)
+			}
+		}
+	}
+	return doc
+}
+
+// add indexes a non-empty value.
+func (d *document) add(name, value string, weight int) {
+	if value = strings.TrimSpace(value); value != "" {
+		d.fields = append(d.fields, field{name: name, value: strings.ToLower(value), weight: weight})
+	}
+}
+
+// Query is a parsed search query.
+type Query struct {
+	Terms []string      // Lower case terms that must all match
+	Types []pb.DataType // Item types to include, all if empty
+	Tags  []string      // Tags an item must all have
+}
+
+// ParseQuery parses a query such as "bank type:card tag:work". Tags can also
+// be written as "#work". Unknown types match nothing.
+func ParseQuery(s string) Query {
+	var q Query
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		switch {
+		case strings.HasPrefix(word, "type:"):
+			name := strings.ToUpper(strings.TrimPrefix(word, "type:"))
+			if name == "" {
+				continue
+			}
+			dataType, ok := pb.DataType_value[typeAliases[name]]
+			if !ok {
+				dataType, ok = pb.DataType_value[name]
+			}
+			if !ok {
+				dataType = -1
+			}
+			q.Types = append(q.Types, pb.DataType(dataType))
+		case strings.HasPrefix(word, "tag:"):
+			if tag := strings.TrimPrefix(word, "tag:"); tag != "" {
+				q.Tags = append(q.Tags, tag)
+			}
+		case strings.HasPrefix(word, "#") && len(word) > 1:
+			q.Tags = append(q.Tags, word[1:])
+		default:
+			q.Terms = append(q.Terms, word)
+		}
+	}
+	return q
+}
+
+// typeAliases maps short type names used in queries to data types.
+var typeAliases = map[string]string{
+	"LOGIN":    pb.DataType_CREDENTIALS.String(),
+	"PASSWORD": pb.DataType_CREDENTIALS.String(),
+	"NOTE":     pb.DataType_TEXT.String(),
+	"FILE":     pb.DataType_BINARY.String(),
+	"SSH":      pb.DataType_SSH_KEY.String(),
+	"TOTP":     pb.DataType_OTP.String(),
+}
+
+// Search returns the items matching the query, best matches first. An empty
+// query returns all items passing the filters. limit <= 0 means no limit.
+func (idx *Index) Search(query string, limit int) []Result {
+	q := ParseQuery(query)
+
+	var results []Result
+	for _, doc := range idx.docs {
+		if !q.matchesFilters(doc) {
+			continue
+		}
+		result, ok := q.score(doc)
+		if ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return strings.ToLower(results[i].Item.Metadata) < strings.ToLower(results[j].Item.Metadata)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// matchesFilters checks the type and tag filters.
+func (q Query) matchesFilters(doc document) bool {
+	if len(q.Types) > 0 {
+		found := false
+		for _, t := range q.Types {
+			if t == doc.item.DataType {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, want := range q.Tags {
+		found := false
+		for _, tag := range doc.tags {
+			if tag == want {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// score matches every term against the document fields and sums the best
+// score per term. Documents missing any term do not match.
+func (q Query) score(doc document) (Result, bool) {
+	result := Result{Item: doc.item, Tags: doc.tags}
+	best := 0
+	for _, term := range q.Terms {
+		termBest := 0
+		for _, f := range doc.fields {
+			s := Score(term, f.value) * f.weight
+			if s > termBest {
+				termBest = s
+			}
+			if s > best {
+				best = s
+				result.Field = f.name
+			}
+		}
+		if termBest == 0 {
+			return Result{}, false
+		}
+		result.Score += termBest
+	}
+	return result, true
+}
+
+// Score rates how well a lower case term matches a lower case text. Exact
+// substrings score highest, especially at word starts; otherwise the term
+// must appear as a subsequence, scored by how close together its letters are.
+// Zero means no match.
+func Score(term, text string) int {
+	if term == "" {
+		return 0
+	}
+	if pos := strings.Index(text, term); pos >= 0 {
+		score := 100 + len(term)
+		if prev, _ := utf8.DecodeLastRuneInString(text[:pos]); pos == 0 || !isWordChar(prev) {
+			score += 50
+		}
+		if len(term) == len(text) {
+			score += 50
+		}
+		return score
+	}
+
+	// Fuzzy subsequence match
+	score, run, ti := 0, 0, 0
+	termRunes := []rune(term)
+	prev := ' '
+	for _, c := range text {
+		if ti < len(termRunes) && c == termRunes[ti] {
+			run++
+			score += 2 * run
+			if !isWordChar(prev) {
+				score += 3
+			}
+			ti++
+		} else {
+			run = 0
+		}
+		prev = c
+	}
+	if ti < len(termRunes) {
+		return 0
+	}
+	// Require a reasonably dense match so short terms do not match everything.
+	if score < 3*len(termRunes) {
+		return 0
+	}
+	return score
+}
+
+// isWordChar reports whether c is part of a word.
+func isWordChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,96 @@
+package search
+
+import (
+	"encoding/json"
+	"testing"
+
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newItem builds a decrypted item for the index
+func newItem(t *testing.T, dataType pb.DataType, metadata string, data map[string]string) *pb.DataItem {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	return &pb.DataItem{DataType: dataType, Metadata: metadata, Data: raw}
+}
+
+// testIndex returns an index over a small vault
+func testIndex(t *testing.T) *Index {
+	return New([]*pb.DataItem{
+		newItem(t, pb.DataType_CREDENTIALS, "Gmail", map[string]string{"login": "alice@gmail.com", "password": "hunter2", "tags": "personal, mail"}),
+		newItem(t, pb.DataType_CREDENTIALS, "Work VPN", map[string]string{"login": "alice", "password": "corp-secret", "totp": "KRSXG5CTMVRXEZLU", "tags": "work"}),
+		newItem(t, pb.DataType_CARD, "Travel card", map[string]string{"card_number": "4111111111111111", "cvv": "123", "cardholder": "Alice Smith", "tags": "finance"}),
+		newItem(t, pb.DataType_TEXT, "Recovery codes", map[string]string{"text": "Backup codes for the mail account", "tags": "mail"}),
+		newItem(t, pb.DataType_OTP, "GitHub 2FA", map[string]string{"uri": "otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP&issuer=GitHub"}),
+		newItem(t, pb.DataType_CUSTOM, "Router", map[string]string{"custom_fields": `[{"name":"ssid","kind":"text","value":"HomeNet"},{"name":"password","kind":"hidden","value":"wifipass"}]`}),
+		newItem(t, pb.DataType_TEMPLATE, "Database", map[string]string{"definition": `{"name":"Database"}`}),
+	})
+}
+
+// metadata returns the descriptions of results
+func metadata(results []Result) []string {
+	names := make([]string, 0, len(results))
+	for _, r := range results {
+		names = append(names, r.Item.Metadata)
+	}
+	return names
+}
+
+// TestSearchRanking ensures description matches rank above field matches
+func TestSearchRanking(t *testing.T) {
+	idx := testIndex(t)
+	assert.Equal(t, 6, idx.Len(), "Templates should not be indexed")
+
+	results := idx.Search("mail", 0)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "Gmail", results[0].Item.Metadata)
+	assert.Contains(t, metadata(results), "Recovery codes")
+}
+
+// TestSearchFuzzy ensures abbreviated terms match
+func TestSearchFuzzy(t *testing.T) {
+	idx := testIndex(t)
+	assert.Equal(t, []string{"Work VPN"}, metadata(idx.Search("wrkvpn", 0)))
+	assert.Equal(t, []string{"Recovery codes"}, metadata(idx.Search("recov cod", 0)))
+	assert.Empty(t, idx.Search("zzz", 0))
+}
+
+// TestScoreWordStart ensures the word start bonus decodes multi-byte characters
+func TestScoreWordStart(t *testing.T) {
+	assert.Greater(t, Score("банк", "клуб банк"), Score("банк", "клуббанк"))
+}
+
+// TestSearchSecrets ensures secret values are not searchable
+func TestSearchSecrets(t *testing.T) {
+	idx := testIndex(t)
+	for _, secret := range []string{"hunter2", "corp-secret", "4111111111111111", "JBSWY3DPEHPK3PXP", "KRSXG5CTMVRXEZLU", "wifipass"} {
+		assert.Empty(t, idx.Search(secret, 0), secret)
+	}
+
+	assert.Equal(t, []string{"Travel card"}, metadata(idx.Search("1111", 0)), "Last four card digits are searchable")
+	assert.Equal(t, []string{"GitHub 2FA"}, metadata(idx.Search("github", 0)))
+	assert.Equal(t, []string{"Router"}, metadata(idx.Search("homenet", 0)))
+}
+
+// TestSearchFilters ensures type and tag filters narrow the results
+func TestSearchFilters(t *testing.T) {
+	idx := testIndex(t)
+	assert.Equal(t, []string{"Gmail", "Work VPN"}, metadata(idx.Search("type:credentials", 0)))
+	assert.Equal(t, []string{"Gmail", "Work VPN"}, metadata(idx.Search("type:login", 0)))
+	assert.Empty(t, idx.Search("alice type:note", 0), "Filters apply together with terms")
+	assert.Equal(t, []string{"Gmail", "Recovery codes"}, metadata(idx.Search("#mail", 0)))
+	assert.Equal(t, []string{"Recovery codes"}, metadata(idx.Search("tag:mail type:text", 0)))
+	assert.Empty(t, idx.Search("type:unknown", 0))
+	assert.Len(t, idx.Search("", 2), 2)
+}
+
+// TestParseQuery ensures filters are separated from search terms
+func TestParseQuery(t *testing.T) {
+	q := ParseQuery("Bank type:card #Work tag:finance")
+	assert.Equal(t, []string{"bank"}, q.Terms)
+	assert.Equal(t, []pb.DataType{pb.DataType_CARD}, q.Types)
+	assert.Equal(t, []string{"work", "finance"}, q.Tags)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package socket
+
+import "os"
+
+// fileOwner reports that file owners are unknown on this system.
+func fileOwner(os.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,32 @@
+package socket
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerUID returns the user ID of the process at the other end of a Unix
+// socket connection, as reported by SO_PEERCRED.
+func peerUID(conn net.Conn) (int, bool, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, false, nil
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if credErr != nil {
+		return 0, false, credErr
+	}
+	return int(cred.Uid), true, nil
+}
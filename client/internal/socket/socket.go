@@ -0,0 +1,115 @@
+// Package socket creates and connects to the Unix sockets of the client
+// agents, which hand out the session token, the vault key and SSH keys.
+//
+// A socket is only trusted in a directory owned by the current user that no
+// one else can access, as anyone able to replace it could impersonate the
+// agent or receive the session. Where the system reports the user of the
+// other end of a connection, connections from other users are refused too.
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrInsecure is returned when a socket, its directory or its peer may be
+// controlled by another user.
+var ErrInsecure = errors.New("insecure agent socket")
+
+// Listen creates a Unix socket only the current user can access, creating
+// its directory if needed. Accepted connections of other users are closed.
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := CheckDir(dir); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &ownerListener{listener}, nil
+}
+
+// Dial connects to the Unix socket at path after checking its directory,
+// and refuses agents run by another user.
+func Dial(path string, timeout time.Duration) (net.Conn, error) {
+	if err := CheckDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPeer(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// CheckDir fails with ErrInsecure unless dir is a directory, not a symbolic
+// link, owned by the current user and inaccessible to other users.
+func CheckDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrInsecure, dir)
+	}
+	if uid, ok := fileOwner(info); ok && uid != os.Getuid() {
+		return fmt.Errorf("%w: %s is owned by user %d", ErrInsecure, dir, uid)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("%w: %s has mode %04o, it must be 0700", ErrInsecure, dir, perm)
+	}
+	return nil
+}
+
+// checkPeer fails with ErrInsecure if the other end of conn is run by
+// another user. It passes where the system does not report the peer.
+func checkPeer(conn net.Conn) error {
+	uid, ok, err := peerUID(conn)
+	if err != nil {
+		return err
+	}
+	if ok && uid != os.Getuid() {
+		return fmt.Errorf("%w: peer is run by user %d", ErrInsecure, uid)
+	}
+	return nil
+}
+
+// ownerListener closes accepted connections of other users.
+type ownerListener struct {
+	net.Listener
+}
+
+// Accept waits for the next connection of the current user.
+func (l *ownerListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPeer(conn); err != nil {
+			conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+}
@@ -0,0 +1,59 @@
+package socket
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListenAndDial ensures a socket is created in a private directory and accepts its owner
+func TestListenAndDial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gophkeeper", "agent.sock")
+	listener, err := Listen(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "Socket should only be accessible by the owner")
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+		accepted <- err
+	}()
+
+	conn, err := Dial(path, time.Second)
+	require.NoError(t, err)
+	conn.Close()
+	assert.NoError(t, <-accepted)
+}
+
+// TestCheckDir ensures directories other users could access or replace are refused
+func TestCheckDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "private")
+	require.NoError(t, os.Mkdir(dir, 0700))
+	assert.NoError(t, CheckDir(dir))
+
+	require.NoError(t, os.Chmod(dir, 0755))
+	assert.ErrorIs(t, CheckDir(dir), ErrInsecure, "Readable by others")
+	_, err := Listen(filepath.Join(dir, "agent.sock"))
+	assert.ErrorIs(t, err, ErrInsecure, "An existing directory is not fixed silently")
+	_, err = Dial(filepath.Join(dir, "agent.sock"), time.Second)
+	assert.ErrorIs(t, err, ErrInsecure)
+
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Chmod(dir, 0700))
+	require.NoError(t, os.Symlink(dir, link))
+	assert.ErrorIs(t, CheckDir(link), ErrInsecure, "Symbolic link")
+
+	_, err = Dial(filepath.Join(t.TempDir(), "missing", "agent.sock"), time.Second)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,121 @@
+// Package sshagent serves SSH keys from the decrypted vault over the
+// ssh-agent protocol, so deploy keys never have to be written to disk.
+//
+// The agent is read-only: clients can list keys and request signatures, but
+// cannot add or remove keys. Every signature can optionally require an
+// interactive confirmation.
+package sshagent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/sshkey"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ErrReadOnly is returned for requests that would modify the vault-backed key list.
+var ErrReadOnly = errors.New("gophkeeper agent is read-only, store keys in the vault instead")
+
+// ErrDenied is returned when the user refuses a signature request.
+var ErrDenied = errors.New("signature request denied")
+
+// ConfirmFunc asks the user whether a key may be used. comment identifies
+// the key. Returning false rejects the signature request.
+type ConfirmFunc func(comment, fingerprint string) bool
+
+// Agent is an ssh-agent backed by vault keys.
+type Agent struct {
+	agent.ExtendedAgent
+	confirm ConfirmFunc
+	mu      sync.Mutex // serialises confirmation prompts
+}
+
+// New creates an agent serving the given keys. confirm may be nil to sign without asking.
+func New(keys []*sshkey.Key, confirm ConfirmFunc) (*Agent, error) {
+	keyring := agent.NewKeyring().(agent.ExtendedAgent)
+	for _, k := range keys {
+		raw, err := k.RawPrivateKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Comment, err)
+		}
+		if err := keyring.Add(agent.AddedKey{PrivateKey: raw, Comment: k.Comment}); err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Comment, err)
+		}
+	}
+	return &Agent{ExtendedAgent: keyring, confirm: confirm}, nil
+}
+
+// Sign signs data after optional confirmation.
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data with the requested algorithm after optional confirmation.
+func (a *Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	if err := a.approve(key); err != nil {
+		return nil, err
+	}
+	return a.ExtendedAgent.SignWithFlags(key, data, flags)
+}
+
+// Add rejects new keys.
+func (a *Agent) Add(agent.AddedKey) error {
+	return ErrReadOnly
+}
+
+// Remove rejects key removal.
+func (a *Agent) Remove(ssh.PublicKey) error {
+	return ErrReadOnly
+}
+
+// RemoveAll rejects key removal.
+func (a *Agent) RemoveAll() error {
+	return ErrReadOnly
+}
+
+// approve asks for confirmation if the agent was created with a ConfirmFunc.
+func (a *Agent) approve(key ssh.PublicKey) error {
+	if a.confirm == nil {
+		return nil
+	}
+
+	comment := ""
+	keys, err := a.List()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if bytes.Equal(k.Marshal(), key.Marshal()) {
+			comment = k.Comment
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.confirm(comment, ssh.FingerprintSHA256(key)) {
+		return ErrDenied
+	}
+	return nil
+}
+
+// Serve accepts connections until the listener is closed and serves each with the agent.
+func Serve(listener net.Listener, a agent.Agent) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = agent.ServeAgent(a, conn)
+		}()
+	}
+}
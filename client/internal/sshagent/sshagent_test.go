@@ -0,0 +1,105 @@
+package sshagent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golangTroshin/gophkeeper/client/internal/socket"
+	"github.com/golangTroshin/gophkeeper/client/internal/sshkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// startAgent serves a over a Unix socket and returns a protocol client.
+func startAgent(t *testing.T, a agent.Agent) agent.ExtendedAgent {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "gk-agent")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	listener, err := socket.Listen(filepath.Join(dir, "agent.sock"))
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go Serve(listener, a)
+
+	info, err := os.Stat(filepath.Join(dir, "agent.sock"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "Socket should only be accessible by the owner")
+
+	conn, err := net.Dial("unix", filepath.Join(dir, "agent.sock"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return agent.NewClient(conn)
+}
+
+// TestAgentListAndSign ensures vault keys are listed and can sign
+func TestAgentListAndSign(t *testing.T) {
+	key, err := sshkey.Generate("deploy")
+	require.NoError(t, err)
+	a, err := New([]*sshkey.Key{key}, nil)
+	require.NoError(t, err)
+
+	client := startAgent(t, a)
+	keys, err := client.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "deploy", keys[0].Comment)
+
+	data := []byte("challenge")
+	sig, err := client.Sign(keys[0], data)
+	require.NoError(t, err)
+
+	pub, err := ssh.ParsePublicKey(keys[0].Marshal())
+	require.NoError(t, err)
+	assert.NoError(t, pub.Verify(data, sig))
+}
+
+// TestAgentConfirm ensures denied confirmations block signatures
+func TestAgentConfirm(t *testing.T) {
+	key, err := sshkey.Generate("prod")
+	require.NoError(t, err)
+
+	var asked []string
+	allow := false
+	a, err := New([]*sshkey.Key{key}, func(comment, fingerprint string) bool {
+		asked = append(asked, comment+" "+fingerprint)
+		return allow
+	})
+	require.NoError(t, err)
+
+	client := startAgent(t, a)
+	keys, err := client.List()
+	require.NoError(t, err)
+
+	_, err = client.Sign(keys[0], []byte("data"))
+	assert.Error(t, err, "Denied requests should fail")
+
+	allow = true
+	_, err = client.Sign(keys[0], []byte("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod " + key.Fingerprint, "prod " + key.Fingerprint}, asked)
+}
+
+// TestAgentReadOnly ensures keys cannot be added or removed
+func TestAgentReadOnly(t *testing.T) {
+	key, err := sshkey.Generate("ro")
+	require.NoError(t, err)
+	a, err := New([]*sshkey.Key{key}, nil)
+	require.NoError(t, err)
+
+	other, err := sshkey.Generate("other")
+	require.NoError(t, err)
+	raw, err := other.RawPrivateKey()
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, a.Add(agent.AddedKey{PrivateKey: raw}), ErrReadOnly)
+	assert.ErrorIs(t, a.RemoveAll(), ErrReadOnly)
+
+	keys, err := a.List()
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
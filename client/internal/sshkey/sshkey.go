@@ -0,0 +1,120 @@
+// Package sshkey generates and imports SSH keys stored as SSH_KEY vault items.
+//
+// Private keys are kept in unencrypted OpenSSH PEM format inside the item
+// data, which is itself encrypted before it leaves the client.
+package sshkey
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Item field names.
+const (
+	FieldPrivateKey  = "private_key"
+	FieldPublicKey   = "public_key"
+	FieldComment     = "comment"
+	FieldFingerprint = "fingerprint"
+)
+
+// Key is an SSH key pair as stored in the vault.
+type Key struct {
+	PrivateKey  string // OpenSSH PEM encoded private key
+	PublicKey   string // authorized_keys line
+	Comment     string // Key comment, usually user@host
+	Fingerprint string // SHA256 fingerprint of the public key
+}
+
+// Generate creates a new Ed25519 key pair.
+func Generate(comment string) (*Key, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return fromRawKey(private, comment)
+}
+
+// Import parses an existing private key in OpenSSH, PKCS#1, PKCS#8 or SEC1
+// PEM format. passphrase is used only if the key is encrypted.
+func Import(pemBytes []byte, passphrase, comment string) (*Key, error) {
+	raw, err := ssh.ParseRawPrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == "" {
+			return nil, fmt.Errorf("private key is encrypted, a passphrase is required")
+		}
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return fromRawKey(raw, comment)
+}
+
+// FromFields restores a key from decrypted item fields.
+func FromFields(fields map[string]string) (*Key, error) {
+	key := &Key{
+		PrivateKey:  fields[FieldPrivateKey],
+		PublicKey:   fields[FieldPublicKey],
+		Comment:     fields[FieldComment],
+		Fingerprint: fields[FieldFingerprint],
+	}
+	if key.PrivateKey == "" {
+		return nil, fmt.Errorf("SSH key item has no private key")
+	}
+	return key, nil
+}
+
+// ParseItem restores a key from the decrypted data of an SSH_KEY item.
+func ParseItem(data []byte) (*Key, error) {
+	var fields map[string]string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid SSH key item: %w", err)
+	}
+	return FromFields(fields)
+}
+
+// Fields returns the item fields stored in the vault.
+func (k *Key) Fields() map[string]string {
+	return map[string]string{
+		FieldPrivateKey:  k.PrivateKey,
+		FieldPublicKey:   k.PublicKey,
+		FieldComment:     k.Comment,
+		FieldFingerprint: k.Fingerprint,
+	}
+}
+
+// RawPrivateKey parses the stored private key.
+func (k *Key) RawPrivateKey() (interface{}, error) {
+	return ssh.ParseRawPrivateKey([]byte(k.PrivateKey))
+}
+
+// fromRawKey builds a Key from a parsed private key.
+func fromRawKey(raw interface{}, comment string) (*Key, error) {
+	signer, err := ssh.NewSignerFromKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(raw, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	public := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	if comment != "" {
+		public += " " + comment
+	}
+	return &Key{
+		PrivateKey:  string(pem.EncodeToMemory(block)),
+		PublicKey:   public,
+		Comment:     comment,
+		Fingerprint: ssh.FingerprintSHA256(signer.PublicKey()),
+	}, nil
+}
@@ -0,0 +1,78 @@
+package sshkey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// TestGenerate ensures generated keys are consistent
+func TestGenerate(t *testing.T) {
+	key, err := Generate("deploy@ci")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key.PublicKey, "ssh-ed25519 "))
+	assert.True(t, strings.HasSuffix(key.PublicKey, " deploy@ci"))
+	assert.True(t, strings.HasPrefix(key.Fingerprint, "SHA256:"))
+
+	signer, err := ssh.ParsePrivateKey([]byte(key.PrivateKey))
+	require.NoError(t, err)
+	assert.Equal(t, key.Fingerprint, ssh.FingerprintSHA256(signer.PublicKey()))
+}
+
+// TestImportPKCS1 ensures existing RSA keys can be imported
+func TestImportPKCS1(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	key, err := Import(pemBytes, "", "legacy")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key.PublicKey, "ssh-rsa "))
+}
+
+// TestImportEncrypted ensures encrypted keys need the right passphrase
+func TestImportEncrypted(t *testing.T) {
+	original, err := Generate("")
+	require.NoError(t, err)
+	raw, err := original.RawPrivateKey()
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(raw, "", []byte("letmein"))
+	require.NoError(t, err)
+	encrypted := pem.EncodeToMemory(block)
+
+	_, err = Import(encrypted, "", "")
+	assert.ErrorContains(t, err, "passphrase is required")
+
+	_, err = Import(encrypted, "wrong", "")
+	assert.Error(t, err)
+
+	key, err := Import(encrypted, "letmein", "")
+	require.NoError(t, err)
+	assert.Equal(t, original.Fingerprint, key.Fingerprint)
+	assert.NotContains(t, key.PrivateKey, "aes256-ctr", "Imported keys should be stored decrypted")
+}
+
+// TestParseItem ensures keys survive the item round trip
+func TestParseItem(t *testing.T) {
+	key, err := Generate("roundtrip")
+	require.NoError(t, err)
+
+	data, err := json.Marshal(key.Fields())
+	require.NoError(t, err)
+
+	restored, err := ParseItem(data)
+	require.NoError(t, err)
+	assert.Equal(t, key, restored)
+
+	_, err = ParseItem([]byte(`{"comment":"no key"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package strength
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PwnedDirEnv names the environment variable pointing to the downloaded
+// Pwned Passwords data.
+const PwnedDirEnv = "GOPHKEEPER_HIBP_PATH"
+
+// prefixLength is the length of the k-anonymity hash prefix used by the
+// Pwned Passwords range API.
+const prefixLength = 5
+
+// ErrRangeMissing is returned when the range file for a hash prefix has not been downloaded.
+var ErrRangeMissing = errors.New("pwned passwords range file not downloaded")
+
+// PwnedChecker looks up password hashes in locally stored Pwned Passwords data.
+//
+// The data can either be a directory of range files named after the 5
+// character SHA-1 prefix (e.g. "21BD1" or "21BD1.txt") containing
+// "SUFFIX:COUNT" lines, as served by the range API, or a single file of
+// "HASH:COUNT" lines sorted by hash, as produced by the official downloader.
+type PwnedChecker struct {
+	path  string
+	isDir bool
+}
+
+// NewPwnedChecker opens the Pwned Passwords data at path.
+func NewPwnedChecker(path string) (*PwnedChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("pwned passwords data: %w", err)
+	}
+	return &PwnedChecker{path: path, isDir: info.IsDir()}, nil
+}
+
+// NewPwnedCheckerFromEnv opens the data configured by PwnedDirEnv. It returns
+// nil when breach checks are not configured.
+func NewPwnedCheckerFromEnv() (*PwnedChecker, error) {
+	path := os.Getenv(PwnedDirEnv)
+	if path == "" {
+		return nil, nil
+	}
+	return NewPwnedChecker(path)
+}
+
+// Count returns how many times the password appears in the breach data.
+func (c *PwnedChecker) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	if c.isDir {
+		return c.countInRange(hash[:prefixLength], hash[prefixLength:])
+	}
+	return c.countInSortedFile(hash)
+}
+
+// countInRange scans the range file of a prefix for the hash suffix.
+func (c *PwnedChecker) countInRange(prefix, suffix string) (int, error) {
+	var f *os.File
+	var err error
+	for _, name := range []string{prefix + ".txt", prefix, strings.ToLower(prefix) + ".txt"} {
+		f, err = os.Open(filepath.Join(c.path, name))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrRangeMissing, prefix)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if hash, count, ok := parseLine(scanner.Text()); ok && hash == suffix {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// countInSortedFile binary searches a file of "HASH:COUNT" lines sorted by hash.
+func (c *PwnedChecker) countInSortedFile(hash string) (int, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// Find the first line starting at or after each probe offset and narrow
+	// the window until it is small enough to scan.
+	low, high := int64(0), info.Size()
+	for high-low > 4096 {
+		mid := (low + high) / 2
+		line, err := lineAfter(f, mid)
+		if err != nil {
+			return 0, err
+		}
+		lineHash, _, ok := parseLine(line)
+		if !ok || lineHash > hash {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+
+	if _, err := f.Seek(low, io.SeekStart); err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(io.LimitReader(f, high-low+4096))
+	if low > 0 {
+		scanner.Scan() // skip the partial line low points into
+	}
+	for scanner.Scan() {
+		lineHash, count, ok := parseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		if lineHash == hash {
+			return count, nil
+		}
+		if lineHash > hash {
+			break
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// lineAfter returns the first complete line that starts after offset.
+func lineAfter(f *os.File, offset int64) (string, error) {
+	buf := make([]byte, 256)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	buf = buf[:n]
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		buf = buf[i+1:]
+	}
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		buf = buf[:i]
+	}
+	return string(buf), nil
+}
+
+// parseLine splits a "HASH:COUNT" line.
+func parseLine(line string) (string, int, bool) {
+	hash, countText, ok := strings.Cut(strings.TrimSpace(line), ":")
+	if !ok {
+		return "", 0, false
+	}
+	count, err := strconv.Atoi(countText)
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.ToUpper(hash), count, true
+}
@@ -0,0 +1,174 @@
+// Package strength estimates password strength and checks passwords against a
+// local copy of the Have I Been Pwned password list.
+//
+// Scoring uses the zxcvbn algorithm. Breach checks never touch the network:
+// they look up the SHA-1 hash of the password in range files downloaded in
+// advance, see PwnedChecker.
+package strength
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	zxcvbn "github.com/nbutton23/zxcvbn-go"
+)
+
+// Score values reported by Check, from 0 (trivial) to 4 (very strong).
+const (
+	MinScore  = 0
+	MaxScore  = 4
+	WeakBelow = 3 // Scores below this value are reported as weak
+)
+
+// Estimate describes how hard a password is to guess.
+type Estimate struct {
+	Score     int      // zxcvbn score from MinScore to MaxScore
+	Entropy   float64  // Estimated entropy in bits
+	CrackTime string   // Human readable offline crack time
+	Warnings  []string // Patterns that make the password easier to guess
+}
+
+// Weak reports whether the password should be replaced.
+func (e Estimate) Weak() bool {
+	return e.Score < WeakBelow
+}
+
+// patternWarnings explains the zxcvbn match patterns to the user.
+var patternWarnings = map[string]string{
+	"dictionary": "contains a common word or password",
+	"spatial":    "contains a keyboard pattern",
+	"repeat":     "contains repeated characters",
+	"sequence":   "contains a character sequence",
+	"date":       "contains a date",
+}
+
+// Check scores a password. userInputs such as the login or item description
+// are treated as dictionary words.
+func Check(password string, userInputs ...string) Estimate {
+	if password == "" {
+		return Estimate{Score: MinScore, CrackTime: "instant", Warnings: []string{"password is empty"}}
+	}
+
+	result := zxcvbn.PasswordStrength(password, userInputs)
+	estimate := Estimate{
+		Score:     result.Score,
+		Entropy:   result.Entropy,
+		CrackTime: result.CrackTimeDisplay,
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range result.MatchSequence {
+		warning, ok := patternWarnings[m.Pattern]
+		if ok && !seen[warning] {
+			seen[warning] = true
+			estimate.Warnings = append(estimate.Warnings, warning)
+		}
+	}
+	if len(password) < 10 {
+		estimate.Warnings = append(estimate.Warnings, "is shorter than 10 characters")
+	}
+	return estimate
+}
+
+// Credential is a stored login checked by Audit.
+type Credential struct {
+	Metadata string
+	Login    string
+	Password string
+}
+
+// CredentialsFromItems extracts logins from decrypted CREDENTIALS items.
+// Items of other types or with undecodable data are ignored.
+func CredentialsFromItems(items []*pb.DataItem) []Credential {
+	var credentials []Credential
+	for _, item := range items {
+		if item.DataType != pb.DataType_CREDENTIALS {
+			continue
+		}
+		var fields map[string]string
+		if err := json.Unmarshal(item.Data, &fields); err != nil {
+			continue
+		}
+		credentials = append(credentials, Credential{
+			Metadata: item.Metadata,
+			Login:    fields["login"],
+			Password: fields["password"],
+		})
+	}
+	return credentials
+}
+
+// Finding is the audit result for a single credential.
+type Finding struct {
+	Credential
+	Estimate Estimate
+	ReusedBy []string // Descriptions of other items sharing the password
+	Breached int      // Times the password appeared in breaches, 0 if unknown or clean
+}
+
+// Problem reports whether the credential is weak, reused or breached.
+func (f Finding) Problem() bool {
+	return f.Estimate.Weak() || len(f.ReusedBy) > 0 || f.Breached > 0
+}
+
+// String summarises the finding without revealing the password.
+func (f Finding) String() string {
+	var problems []string
+	if f.Breached > 0 {
+		problems = append(problems, fmt.Sprintf("breached (seen %d times)", f.Breached))
+	}
+	if f.Estimate.Weak() {
+		problems = append(problems, fmt.Sprintf("weak (score %d/%d, cracked in %s)", f.Estimate.Score, MaxScore, f.Estimate.CrackTime))
+	}
+	if len(f.ReusedBy) > 0 {
+		problems = append(problems, "reused by "+strings.Join(f.ReusedBy, ", "))
+	}
+	if len(problems) == 0 {
+		problems = append(problems, "ok")
+	}
+	return fmt.Sprintf("%s (%s): %s", f.Metadata, f.Login, strings.Join(problems, "; "))
+}
+
+// Audit checks every credential for weak, reused and breached passwords.
+// checker may be nil, in which case breach checks are skipped. Findings with
+// problems are sorted first.
+func Audit(credentials []Credential, checker *PwnedChecker) ([]Finding, error) {
+	byPassword := make(map[string][]string)
+	for _, c := range credentials {
+		if c.Password != "" {
+			byPassword[c.Password] = append(byPassword[c.Password], c.Metadata)
+		}
+	}
+
+	findings := make([]Finding, 0, len(credentials))
+	for _, c := range credentials {
+		f := Finding{Credential: c, Estimate: Check(c.Password, c.Login, c.Metadata)}
+
+		skipped := false
+		for _, other := range byPassword[c.Password] {
+			if other == c.Metadata && !skipped {
+				skipped = true // the credential itself
+				continue
+			}
+			f.ReusedBy = append(f.ReusedBy, other)
+		}
+
+		if checker != nil && c.Password != "" {
+			count, err := checker.Count(c.Password)
+			if err != nil && !errors.Is(err, ErrRangeMissing) {
+				return nil, err
+			}
+			f.Breached = count
+		}
+		findings = append(findings, f)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Problem() && !findings[j].Problem()
+	})
+	return findings, nil
+}
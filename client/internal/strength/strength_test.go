@@ -0,0 +1,115 @@
+package strength
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// TestCheck ensures common passwords score low and random ones score high
+func TestCheck(t *testing.T) {
+	weak := Check("password1")
+	assert.True(t, weak.Weak(), "Common passwords should be weak")
+	assert.Contains(t, weak.Warnings, "contains a common word or password")
+
+	strong := Check("c7#Vq!92mZ$wL0pX^tR4")
+	assert.False(t, strong.Weak(), "Random passwords should not be weak")
+	assert.Equal(t, MaxScore, strong.Score)
+
+	assert.True(t, Check("").Weak(), "Empty passwords should be weak")
+}
+
+// TestCheckUserInputs ensures the login is treated as a dictionary word
+func TestCheckUserInputs(t *testing.T) {
+	without := Check("johnsmith1990")
+	with := Check("johnsmith1990", "johnsmith")
+	assert.LessOrEqual(t, with.Entropy, without.Entropy)
+}
+
+// TestAuditReused ensures shared passwords are reported for every item
+func TestAuditReused(t *testing.T) {
+	findings, err := Audit([]Credential{
+		{Metadata: "Unique", Login: "a", Password: "c7#Vq!92mZ$wL0pX^tR4"},
+		{Metadata: "Mail", Login: "b", Password: "Tr0ub4dour&3-horse"},
+		{Metadata: "Forum", Login: "c", Password: "Tr0ub4dour&3-horse"},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, findings, 3)
+
+	assert.Equal(t, []string{"Forum"}, findings[0].ReusedBy)
+	assert.Equal(t, []string{"Mail"}, findings[1].ReusedBy)
+	assert.Equal(t, "Unique", findings[2].Metadata, "Findings without problems should be sorted last")
+	assert.False(t, findings[2].Problem())
+	assert.NotContains(t, findings[0].String(), "Tr0ub4dour", "Findings should not reveal passwords")
+}
+
+// TestPwnedCheckerRangeDir ensures range files are looked up by prefix
+func TestPwnedCheckerRangeDir(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("password1")
+	content := fmt.Sprintf("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:2427\r\n", hash[5:])
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(content), 0600))
+
+	checker, err := NewPwnedChecker(dir)
+	require.NoError(t, err)
+
+	count, err := checker.Count("password1")
+	require.NoError(t, err)
+	assert.Equal(t, 2427, count)
+
+	_, err = checker.Count("some other password")
+	assert.ErrorIs(t, err, ErrRangeMissing)
+}
+
+// TestPwnedCheckerSortedFile ensures the combined hash file is binary searched
+func TestPwnedCheckerSortedFile(t *testing.T) {
+	var lines []string
+	for i := 0; i < 5000; i++ {
+		lines = append(lines, fmt.Sprintf("%s:%d", sha1Hex(fmt.Sprint("pw", i)), i+1))
+	}
+	sort.Strings(lines)
+
+	file := filepath.Join(t.TempDir(), "pwnedpasswords.txt")
+	require.NoError(t, os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+	checker, err := NewPwnedChecker(file)
+	require.NoError(t, err)
+
+	for _, i := range []int{0, 1, 2500, 4998, 4999} {
+		count, err := checker.Count(fmt.Sprint("pw", i))
+		require.NoError(t, err)
+		assert.Equal(t, i+1, count, "Count for pw%d", i)
+	}
+
+	count, err := checker.Count("not in the list")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+// TestAuditBreached ensures breach counts are attached to findings
+func TestAuditBreached(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("hunter2")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]), []byte(hash[5:]+":17\n"), 0600))
+
+	checker, err := NewPwnedChecker(dir)
+	require.NoError(t, err)
+
+	findings, err := Audit([]Credential{{Metadata: "Old", Login: "x", Password: "hunter2"}}, checker)
+	require.NoError(t, err)
+	assert.Equal(t, 17, findings[0].Breached)
+	assert.Contains(t, findings[0].String(), "breached")
+}
@@ -25,6 +25,9 @@ const (
 	GophKeeperService_MasterSeedRetrieve_FullMethodName = "/gophkeeper.GophKeeperService/MasterSeedRetrieve"
 	GophKeeperService_StoreData_FullMethodName          = "/gophkeeper.GophKeeperService/StoreData"
 	GophKeeperService_RetrieveData_FullMethodName       = "/gophkeeper.GophKeeperService/RetrieveData"
+	GophKeeperService_ListItems_FullMethodName          = "/gophkeeper.GophKeeperService/ListItems"
+	GophKeeperService_GetItem_FullMethodName            = "/gophkeeper.GophKeeperService/GetItem"
+	GophKeeperService_GetUsage_FullMethodName           = "/gophkeeper.GophKeeperService/GetUsage"
 )
 
 // GophKeeperServiceClient is the client API for GophKeeperService service.
@@ -37,6 +40,9 @@ type GophKeeperServiceClient interface {
 	MasterSeedRetrieve(ctx context.Context, in *MasterSeedRetrieveRequest, opts ...grpc.CallOption) (*MasterSeedRetrieveResponse, error)
 	StoreData(ctx context.Context, in *StoreDataRequest, opts ...grpc.CallOption) (*StoreDataResponse, error)
 	RetrieveData(ctx context.Context, in *RetrieveDataRequest, opts ...grpc.CallOption) (*RetrieveDataResponse, error)
+	ListItems(ctx context.Context, in *ListItemsRequest, opts ...grpc.CallOption) (*ListItemsResponse, error)
+	GetItem(ctx context.Context, in *GetItemRequest, opts ...grpc.CallOption) (*GetItemResponse, error)
+	GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error)
 }
 
 type gophKeeperServiceClient struct {
@@ -107,6 +113,36 @@ func (c *gophKeeperServiceClient) RetrieveData(ctx context.Context, in *Retrieve
 	return out, nil
 }
 
+func (c *gophKeeperServiceClient) ListItems(ctx context.Context, in *ListItemsRequest, opts ...grpc.CallOption) (*ListItemsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListItemsResponse)
+	err := c.cc.Invoke(ctx, GophKeeperService_ListItems_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperServiceClient) GetItem(ctx context.Context, in *GetItemRequest, opts ...grpc.CallOption) (*GetItemResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetItemResponse)
+	err := c.cc.Invoke(ctx, GophKeeperService_GetItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophKeeperServiceClient) GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUsageResponse)
+	err := c.cc.Invoke(ctx, GophKeeperService_GetUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GophKeeperServiceServer is the server API for GophKeeperService service.
 // All implementations must embed UnimplementedGophKeeperServiceServer
 // for forward compatibility.
@@ -117,6 +153,9 @@ type GophKeeperServiceServer interface {
 	MasterSeedRetrieve(context.Context, *MasterSeedRetrieveRequest) (*MasterSeedRetrieveResponse, error)
 	StoreData(context.Context, *StoreDataRequest) (*StoreDataResponse, error)
 	RetrieveData(context.Context, *RetrieveDataRequest) (*RetrieveDataResponse, error)
+	ListItems(context.Context, *ListItemsRequest) (*ListItemsResponse, error)
+	GetItem(context.Context, *GetItemRequest) (*GetItemResponse, error)
+	GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error)
 	mustEmbedUnimplementedGophKeeperServiceServer()
 }
 
@@ -145,6 +184,15 @@ func (UnimplementedGophKeeperServiceServer) StoreData(context.Context, *StoreDat
 func (UnimplementedGophKeeperServiceServer) RetrieveData(context.Context, *RetrieveDataRequest) (*RetrieveDataResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RetrieveData not implemented")
 }
+func (UnimplementedGophKeeperServiceServer) ListItems(context.Context, *ListItemsRequest) (*ListItemsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListItems not implemented")
+}
+func (UnimplementedGophKeeperServiceServer) GetItem(context.Context, *GetItemRequest) (*GetItemResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetItem not implemented")
+}
+func (UnimplementedGophKeeperServiceServer) GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsage not implemented")
+}
 func (UnimplementedGophKeeperServiceServer) mustEmbedUnimplementedGophKeeperServiceServer() {}
 func (UnimplementedGophKeeperServiceServer) testEmbeddedByValue()                           {}
 
@@ -274,6 +322,60 @@ func _GophKeeperService_RetrieveData_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GophKeeperService_ListItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListItemsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServiceServer).ListItems(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GophKeeperService_ListItems_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServiceServer).ListItems(ctx, req.(*ListItemsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeperService_GetItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServiceServer).GetItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GophKeeperService_GetItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServiceServer).GetItem(ctx, req.(*GetItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GophKeeperService_GetUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophKeeperServiceServer).GetUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GophKeeperService_GetUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophKeeperServiceServer).GetUsage(ctx, req.(*GetUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GophKeeperService_ServiceDesc is the grpc.ServiceDesc for GophKeeperService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -305,6 +407,18 @@ var GophKeeperService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RetrieveData",
 			Handler:    _GophKeeperService_RetrieveData_Handler,
 		},
+		{
+			MethodName: "ListItems",
+			Handler:    _GophKeeperService_ListItems_Handler,
+		},
+		{
+			MethodName: "GetItem",
+			Handler:    _GophKeeperService_GetItem_Handler,
+		},
+		{
+			MethodName: "GetUsage",
+			Handler:    _GophKeeperService_GetUsage_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "gophkeeper.proto",
@@ -2,58 +2,103 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 
 	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
 	"github.com/golangTroshin/gophkeeper/server/internal/database"
 	"github.com/golangTroshin/gophkeeper/server/internal/handlers"
-	"github.com/joho/godotenv"
+	"github.com/golangTroshin/gophkeeper/server/internal/repository"
+	"github.com/golangTroshin/gophkeeper/server/internal/tlsconfig"
 )
 
 // main is the entry point of the GophKeeper gRPC server.
-// It initializes the environment, sets up the database, and starts the server.
+// It loads the configuration, sets up the database, and starts the server,
+// or manages the database schema when run as "gophkeeper-server migrate".
+// Invalid settings stop the server before it accepts connections.
 func main() {
-	// Load environment variables from .env file.
-	err := godotenv.Load("../.env")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Printf("Error loading .env file: %v", err)
+		log.Fatalf("Invalid configuration: %v", err)
 	}
+	slog.SetDefault(cfg.Log.NewLogger())
 
-	// Initialize the database connection.
-	err = database.InitDB()
+	// Initialize the database connection and apply pending migrations.
+	db, err := database.InitDB(cfg.Database)
 	if err != nil {
-		log.Printf("Failed to initialize database: %v", err)
+		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer func() {
 		log.Println("Closing database connection...")
-		sqlDB, err := database.DB.DB()
-		if err != nil {
-			log.Printf("Error getting underlying DB: %v", err)
+		if err := database.Close(db); err != nil {
+			log.Printf("Error closing database: %v", err)
+			return
 		}
-		sqlDB.Close()
 		log.Println("Database connection closed.")
 	}()
 
-	grpcServer := grpc.NewServer()
+	var serverOpts []grpc.ServerOption
+	if cfg.TLS.CertFile != "" {
+		certs, err := tlsconfig.New(cfg.TLS.Options())
+		if err != nil {
+			log.Fatalf("Failed to configure TLS: %v", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(certs.Config())))
+
+		// Certificates are reloaded when their files change, SIGHUP forces it.
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := certs.Reload(); err != nil {
+					log.Printf("Failed to reload TLS certificates: %v", err)
+					continue
+				}
+				log.Println("TLS certificates reloaded.")
+			}
+		}()
+	} else {
+		slog.Warn("No TLS certificate is configured, serving without TLS. Passwords cross the network in clear text.")
+	}
+	if cfg.JWT.Secret == "" {
+		slog.Warn("No JWT secret is configured, using a random one. Sessions end when the server restarts.")
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
 
-	gophKeeperServer := &handlers.GophKeeperServer{DB: database.DB}
+	gophKeeperServer := handlers.NewServer(cfg, repository.NewRepository(db), time.Now, slog.Default())
 	pb.RegisterGophKeeperServiceServer(grpcServer, gophKeeperServer)
 
 	reflection.Register(grpcServer)
 
-	listener, err := net.Listen("tcp", ":50051")
+	listener, err := net.Listen("tcp", cfg.Listen)
 	if err != nil {
-		log.Printf("Failed to listen: %v", err)
+		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	log.Println("Server is listening on port 50051")
+	slog.Info("Server is listening", "address", listener.Addr().String())
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -63,7 +108,6 @@ func main() {
 		log.Println("Shutting down gracefully...")
 		grpcServer.GracefulStop()
 		log.Println("gRPC server stopped.")
-		os.Exit(0)
 	}()
 
 	if err := grpcServer.Serve(listener); err != nil {
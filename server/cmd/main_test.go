@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -11,8 +14,10 @@ import (
 	"google.golang.org/grpc"
 
 	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
 	"github.com/golangTroshin/gophkeeper/server/internal/database"
 	"github.com/golangTroshin/gophkeeper/server/internal/handlers"
+	"github.com/golangTroshin/gophkeeper/server/internal/repository"
 )
 
 // TestGRPCServerStartup ensures the gRPC server starts and handles shutdown correctly.
@@ -25,7 +30,14 @@ func TestGRPCServerStartup(t *testing.T) {
 	defer listener.Close()
 
 	grpcServer := grpc.NewServer()
-	gophKeeperServer := &handlers.GophKeeperServer{DB: database.DB}
+	db, err := database.InitDB(config.Database{Driver: config.DriverSQLite, Path: database.MemoryPath})
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer database.Close(db)
+
+	cfg := config.Defaults()
+	gophKeeperServer := handlers.NewServer(&cfg, repository.NewRepository(db), time.Now, nil)
 	pb.RegisterGophKeeperServiceServer(grpcServer, gophKeeperServer)
 
 	// Channel to listen for shutdown signals
@@ -51,3 +63,32 @@ func TestGRPCServerStartup(t *testing.T) {
 	// Gracefully stop the server
 	grpcServer.GracefulStop()
 }
+
+// TestRunMigrate ensures the migrate subcommand applies, reverts and lists migrations.
+func TestRunMigrate(t *testing.T) {
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "vault.db"))
+
+	var out bytes.Buffer
+	if err := runMigrate([]string{"up"}, &out); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if strings.Contains(out.String(), "pending") || !strings.Contains(out.String(), "0001_initial") {
+		t.Fatalf("Expected all migrations to be applied, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runMigrate([]string{"down", "2"}, &out); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if strings.Count(out.String(), "pending") != 2 {
+		t.Fatalf("Expected two pending migrations, got:\n%s", out.String())
+	}
+
+	if err := runMigrate([]string{"sideways"}, &out); err == nil {
+		t.Fatal("Expected an error for an unknown action")
+	}
+	if err := runMigrate(nil, &out); err == nil {
+		t.Fatal("Expected the usage without an action")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
+	"github.com/golangTroshin/gophkeeper/server/internal/database"
+)
+
+// migrateUsage describes the migrate subcommand.
+const migrateUsage = `usage: gophkeeper-server migrate <up|down [steps]|status> [flags]
+
+  up            apply all pending migrations
+  down [steps]  revert the last steps migrations (default 1)
+  status        list migrations and when they were applied
+
+Flags are those of the server, e.g. -config.`
+
+// runMigrate implements "gophkeeper-server migrate", managing the database
+// schema without starting the server.
+func runMigrate(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	action, args := args[0], args[1:]
+	steps := 1
+	if action == "down" && len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			if n <= 0 {
+				return fmt.Errorf("invalid number of steps %d", n)
+			}
+			steps, args = n, args[1:]
+		}
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	switch action {
+	case "up":
+		err = database.Migrate(db)
+	case "down":
+		err = database.Rollback(db, steps)
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate action %q\n%s", action, migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := database.Status(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range status {
+		state := "pending"
+		if m.AppliedAt != nil {
+			state = "applied " + m.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(out, "%04d_%-24s %s\n", m.Version, m.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+// Package config loads the configuration of the GophKeeper server.
+//
+// Settings are read, in increasing priority, from built-in defaults, an
+// optional YAML file, an optional file of environment variables (-env-file),
+// environment variables and command line flags:
+//
+//	listen: ":50051"
+//	database:
+//	  driver: postgres
+//	  host: localhost
+//	  port: 5432
+//	  user: gophkeeper
+//	  password: secret
+//	  name: gophkeeper
+//	  sslmode: disable
+//	jwt:
+//	  secret_file: /etc/gophkeeper/jwt.key
+//	  ttl: 24h
+//	tls:
+//	  cert_file: /etc/gophkeeper/server.crt
+//	  key_file: /etc/gophkeeper/server.key
+//	limits:
+//	  max_item_size: 3145728
+//	  max_user_bytes: 268435456
+//	  max_user_items: 10000
+//	log:
+//	  level: info
+//	  format: text
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/server/internal/tlsconfig"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the configuration file when the -config flag is not given.
+const FileEnv = "GOPHKEEPER_SERVER_CONFIG"
+
+// MinJWTSecretSize is the minimum length of the token signing secret in bytes.
+const MinJWTSecretSize = 32
+
+// Config is the complete server configuration.
+type Config struct {
+	Listen   string   `yaml:"listen"`
+	Database Database `yaml:"database"`
+	JWT      JWT      `yaml:"jwt"`
+	TLS      TLS      `yaml:"tls"`
+	Limits   Limits   `yaml:"limits"`
+	Log      Log      `yaml:"log"`
+}
+
+// Storage drivers.
+const (
+	DriverPostgres = "postgres" // PostgreSQL server
+	DriverSQLite   = "sqlite"   // SQLite file, for single-user and small-team servers
+)
+
+// Database selects the storage driver and locates the database.
+type Database struct {
+	Driver   string `yaml:"driver"` // postgres or sqlite
+	Path     string `yaml:"path"`   // SQLite database file
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+// DSN returns the PostgreSQL connection string.
+func (d Database) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteDSN(d.Host), d.Port, quoteDSN(d.User), quoteDSN(d.Password), quoteDSN(d.Name), quoteDSN(d.SSLMode))
+}
+
+// quoteDSN quotes a connection string value, which may be empty or contain spaces.
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// JWT configures session tokens.
+type JWT struct {
+	Secret     string        `yaml:"secret"`      // Signing secret, see SecretFile
+	SecretFile string        `yaml:"secret_file"` // File holding the secret, preferred over Secret
+	TTL        time.Duration `yaml:"ttl"`         // Lifetime of issued tokens
+}
+
+// TLS locates the certificates, see tlsconfig.Options. TLS is disabled
+// without a certificate.
+type TLS struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+	ClientAuth   string `yaml:"client_auth"`
+}
+
+// Options converts the settings for tlsconfig.New.
+func (t TLS) Options() tlsconfig.Options {
+	return tlsconfig.Options{CertFile: t.CertFile, KeyFile: t.KeyFile, ClientCAFile: t.ClientCAFile, ClientAuth: t.ClientAuth}
+}
+
+// Limits protect the server from oversized requests and keep one account
+// from filling the database.
+type Limits struct {
+	MaxItemSize  int   `yaml:"max_item_size"`  // Largest accepted item in bytes
+	MaxUserBytes int64 `yaml:"max_user_bytes"` // Storage quota per user in bytes, unlimited if 0
+	MaxUserItems int   `yaml:"max_user_items"` // Item quota per user, unlimited if 0
+}
+
+// Log configures server logging.
+type Log struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // text or json
+}
+
+// Defaults returns the configuration used when nothing is set.
+func Defaults() Config {
+	return Config{
+		Listen:   ":50051",
+		Database: Database{Driver: DriverPostgres, Host: "localhost", Port: 5432, Name: "gophkeeper", SSLMode: "disable"},
+		JWT:      JWT{TTL: 24 * time.Hour},
+		Limits:   Limits{MaxItemSize: 3 << 20, MaxUserBytes: 256 << 20, MaxUserItems: 10000},
+		Log:      Log{Level: "info", Format: "text"},
+	}
+}
+
+// Load builds the configuration from the file named by -config or FileEnv,
+// the file named by -env-file, the environment and the command line arguments.
+func Load(args []string) (*Config, error) {
+	cfg := Defaults()
+
+	fs := flag.NewFlagSet("gophkeeper-server", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv(FileEnv), "YAML configuration file")
+	envFile := fs.String("env-file", "", "file of KEY=value environment variables, overridden by the environment")
+	listen := fs.String("listen", "", "address to listen on, e.g. :50051")
+	logLevel := fs.String("log-level", "", "log level: debug, info, warn or error")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *file != "" {
+		if err := cfg.loadFile(*file); err != nil {
+			return nil, err
+		}
+	}
+	getenv := os.Getenv
+	if *envFile != "" {
+		vars, err := godotenv.Read(*envFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", *envFile, err)
+		}
+		getenv = func(key string) string {
+			if v := os.Getenv(key); v != "" {
+				return v
+			}
+			return vars[key]
+		}
+	}
+	if err := cfg.applyEnv(getenv); err != nil {
+		return nil, err
+	}
+	if *listen != "" {
+		cfg.Listen = *listen
+	}
+	if *logLevel != "" {
+		cfg.Log.Level = *logLevel
+	}
+
+	if err := cfg.loadSecret(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile reads a YAML file over the current settings.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides settings with the environment variables returned by getenv.
+func (c *Config) applyEnv(getenv func(string) string) error {
+	texts := map[string]*string{
+		"LISTEN_ADDR":        &c.Listen,
+		"DB_DRIVER":          &c.Database.Driver,
+		"DB_PATH":            &c.Database.Path,
+		"DB_HOST":            &c.Database.Host,
+		"DB_USER":            &c.Database.User,
+		"DB_PASSWORD":        &c.Database.Password,
+		"DB_NAME":            &c.Database.Name,
+		"DB_SSLMODE":         &c.Database.SSLMode,
+		"JWT_SECRET":         &c.JWT.Secret,
+		"JWT_SECRET_FILE":    &c.JWT.SecretFile,
+		"TLS_CERT_FILE":      &c.TLS.CertFile,
+		"TLS_KEY_FILE":       &c.TLS.KeyFile,
+		"TLS_CLIENT_CA_FILE": &c.TLS.ClientCAFile,
+		"TLS_CLIENT_AUTH":    &c.TLS.ClientAuth,
+		"LOG_LEVEL":          &c.Log.Level,
+		"LOG_FORMAT":         &c.Log.Format,
+	}
+	for env, value := range texts {
+		if v := getenv(env); v != "" {
+			*value = v
+		}
+	}
+
+	ints := map[string]*int{
+		"DB_PORT":        &c.Database.Port,
+		"MAX_ITEM_SIZE":  &c.Limits.MaxItemSize,
+		"MAX_USER_ITEMS": &c.Limits.MaxUserItems,
+	}
+	for env, value := range ints {
+		if v := getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: must be a number", env, v)
+			}
+			*value = n
+		}
+	}
+
+	if v := getenv("MAX_USER_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid MAX_USER_BYTES %q: must be a number", v)
+		}
+		c.Limits.MaxUserBytes = n
+	}
+
+	if v := getenv("JWT_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid JWT_TTL %q: %w", v, err)
+		}
+		c.JWT.TTL = ttl
+	}
+	return nil
+}
+
+// loadSecret reads the JWT secret file.
+func (c *Config) loadSecret() error {
+	if c.JWT.SecretFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.JWT.SecretFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the JWT secret: %w", err)
+	}
+	c.JWT.Secret = strings.TrimSpace(string(data))
+	return nil
+}
+
+// Validate reports all invalid settings.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Listen == "" {
+		errs = append(errs, errors.New("listen address is required"))
+	}
+	switch c.Database.Driver {
+	case DriverPostgres:
+		if c.Database.Host == "" || c.Database.Name == "" {
+			errs = append(errs, errors.New("database host and name are required"))
+		}
+		if c.Database.Port <= 0 || c.Database.Port > 65535 {
+			errs = append(errs, fmt.Errorf("invalid database port %d", c.Database.Port))
+		}
+	case DriverSQLite:
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("the SQLite database path is required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown database driver %q, use %s or %s", c.Database.Driver, DriverPostgres, DriverSQLite))
+	}
+	if c.JWT.Secret != "" && len(c.JWT.Secret) < MinJWTSecretSize {
+		errs = append(errs, fmt.Errorf("the JWT secret must have at least %d bytes", MinJWTSecretSize))
+	}
+	if c.JWT.TTL <= 0 {
+		errs = append(errs, errors.New("the JWT lifetime must be positive"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("the TLS certificate and key files must be set together"))
+	}
+	if c.TLS.CertFile == "" && (c.TLS.ClientCAFile != "" || c.TLS.ClientAuth != "") {
+		errs = append(errs, errors.New("client certificates require TLS"))
+	}
+	if c.Limits.MaxItemSize <= 0 {
+		errs = append(errs, errors.New("the maximum item size must be positive"))
+	}
+	if c.Limits.MaxUserBytes < 0 || c.Limits.MaxUserItems < 0 {
+		errs = append(errs, errors.New("user quotas must not be negative, use 0 for unlimited"))
+	}
+	if _, err := c.Log.level(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("unknown log format %q, use text or json", c.Log.Format))
+	}
+	return errors.Join(errs...)
+}
+
+// level parses the log level.
+func (l Log) level() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, use debug, info, warn or error", l.Level)
+	}
+	return level, nil
+}
+
+// NewLogger creates the logger described by the configuration.
+func (l Log) NewLogger() *slog.Logger {
+	level, _ := l.level() // Checked by Validate
+	opts := &slog.HandlerOptions{Level: level}
+	if l.Format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
@@ -0,0 +1,135 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
+)
+
+// clearEnv unsets the variables read by Load for the duration of a test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, env := range []string{
+		config.FileEnv, "LISTEN_ADDR", "DB_DRIVER", "DB_PATH", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE",
+		"JWT_SECRET", "JWT_SECRET_FILE", "JWT_TTL", "TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_CLIENT_CA_FILE",
+		"TLS_CLIENT_AUTH", "LOG_LEVEL", "LOG_FORMAT", "MAX_ITEM_SIZE", "MAX_USER_BYTES", "MAX_USER_ITEMS",
+	} {
+		t.Setenv(env, "")
+		os.Unsetenv(env)
+	}
+}
+
+// TestLoadPrecedence ensures flags override the environment, which overrides the file
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "jwt.key")
+	if err := os.WriteFile(secretFile, []byte(strings.Repeat("s", 40)+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	file := filepath.Join(dir, "server.yaml")
+	yaml := "listen: \":7000\"\ndatabase:\n  host: db.internal\n  port: 6432\njwt:\n  secret_file: " + secretFile + "\n  ttl: 1h\nlog:\n  level: warn\n"
+	if err := os.WriteFile(file, []byte(yaml), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("DB_HOST", "db.env")
+	t.Setenv("LISTEN_ADDR", ":8000")
+
+	cfg, err := config.Load([]string{"-config", file, "-listen", ":9000"})
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Listen != ":9000" {
+		t.Fatalf("Expected the flag to win, got %q", cfg.Listen)
+	}
+	if cfg.Database.Host != "db.env" || cfg.Database.Port != 6432 {
+		t.Fatalf("Expected host from the environment and port from the file, got %+v", cfg.Database)
+	}
+	if cfg.JWT.Secret != strings.Repeat("s", 40) || cfg.JWT.TTL != time.Hour {
+		t.Fatalf("Unexpected JWT settings %+v", cfg.JWT)
+	}
+	if cfg.Log.Level != "warn" || cfg.Log.Format != "text" || cfg.Limits.MaxItemSize != 3<<20 {
+		t.Fatalf("Expected defaults for unset values, got %+v %+v", cfg.Log, cfg.Limits)
+	}
+}
+
+// TestLoadEnvFile ensures an env file only fills in what the environment leaves unset
+func TestLoadEnvFile(t *testing.T) {
+	clearEnv(t)
+	file := filepath.Join(t.TempDir(), "server.env")
+	if err := os.WriteFile(file, []byte("LISTEN_ADDR=:7000\nDB_HOST=db.file\nMAX_USER_ITEMS=5\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	t.Setenv("DB_HOST", "db.env")
+
+	cfg, err := config.Load([]string{"-env-file", file})
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Listen != ":7000" || cfg.Limits.MaxUserItems != 5 {
+		t.Fatalf("Expected settings from the env file, got %q %+v", cfg.Listen, cfg.Limits)
+	}
+	if cfg.Database.Host != "db.env" {
+		t.Fatalf("Expected the environment to win, got %q", cfg.Database.Host)
+	}
+	if os.Getenv("LISTEN_ADDR") != "" {
+		t.Fatalf("The env file must not change the process environment")
+	}
+
+	if _, err := config.Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")}); err == nil {
+		t.Fatalf("Expected an error for a missing env file")
+	}
+}
+
+// TestLoadInvalid ensures invalid settings are reported together
+func TestLoadInvalid(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "short")
+	t.Setenv("TLS_CERT_FILE", "server.crt")
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("MAX_USER_ITEMS", "-1")
+
+	_, err := config.Load(nil)
+	if err == nil {
+		t.Fatalf("Expected an error for invalid settings")
+	}
+	for _, want := range []string{"JWT secret", "TLS certificate and key", "log format", "quotas"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("Expected %q in %v", want, err)
+		}
+	}
+
+	clearEnv(t)
+	t.Setenv("DB_PORT", "postgres")
+	if _, err := config.Load(nil); err == nil {
+		t.Fatalf("Expected an error for a non-numeric port")
+	}
+
+	clearEnv(t)
+	t.Setenv("DB_DRIVER", "sqlite")
+	if _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "SQLite database path") {
+		t.Fatalf("Expected an error for SQLite without a path, got %v", err)
+	}
+
+	clearEnv(t)
+	file := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(file, []byte("listne: \":7000\"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := config.Load([]string{"-config", file}); err == nil {
+		t.Fatalf("Expected an error for an unknown field")
+	}
+}
+
+// TestDSN ensures empty values and quotes do not break the connection string
+func TestDSN(t *testing.T) {
+	db := config.Database{Host: "localhost", Port: 5432, Password: `it's a \ secret`, Name: "vault"}
+	want := `host='localhost' port=5432 user='' password='it\'s a \\ secret' dbname='vault' sslmode=''`
+	if got := db.DSN(); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+}
@@ -1,53 +1,106 @@
 // Package database handles database connection and initialization for the GophKeeper server.
+//
+// The storage driver is selected by configuration: PostgreSQL for shared
+// deployments, or an SQLite file so a small team can run the server as a
+// single binary without a database server.
 package database
 
 import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"time"
 
-	"github.com/golangTroshin/gophkeeper/server/internal/models"
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// DB is a global variable that holds the database connection.
-var DB *gorm.DB
+// MemoryPath is the SQLite path of a private in-memory database, which is
+// lost when it is closed. It suits tests and demonstrations.
+const MemoryPath = ":memory:"
 
-// InitDB initializes the database connection using environment variables.
-// It sets up the PostgreSQL connection and performs automatic migrations.
-//
-// Environment Variables:
-//   - DB_HOST: Database host address
-//   - DB_PORT: Database port
-//   - DB_USER: Database username
-//   - DB_PASSWORD: Database password
-//   - DB_NAME: Database name
-//   - DB_SSLMODE: SSL mode for the connection (e.g., "disable", "require")
+// InitDB opens the database described by cfg and applies pending schema
+// migrations.
 //
 // Returns an error if the connection or migration fails.
-func InitDB() error {
-	var err error
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbSSLMode := os.Getenv("DB_SSLMODE")
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode)
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func InitDB(cfg config.Database) (*gorm.DB, error) {
+	db, err := Open(cfg)
 	if err != nil {
 		log.Printf("Failed to connect to the database: %v", err)
-		return err
+		return nil, err
 	}
 
-	if err := DB.AutoMigrate(&models.User{}, &models.Vault{}); err != nil {
+	if err := Migrate(db); err != nil {
 		log.Printf("Failed to migrate database: %v", err)
+		Close(db)
+		return nil, err
+	}
+
+	log.Printf("Database (%s) connected and migrated successfully.", cfg.Driver)
+	return db, nil
+}
+
+// Close closes the connections of db.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
 		return err
 	}
+	return sqlDB.Close()
+}
+
+// Open connects to the database with the driver selected by cfg.
+func Open(cfg config.Database) (*gorm.DB, error) {
+	switch cfg.Driver {
+	case "", config.DriverPostgres:
+		return gorm.Open(postgres.Open(cfg.DSN()), gormConfig())
+	case config.DriverSQLite:
+		return openSQLite(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
+
+// gormConfig returns the GORM settings shared by all drivers. Timestamps are
+// stored in UTC, as SQLite compares them as text.
+func gormConfig() *gorm.Config {
+	return &gorm.Config{NowFunc: func() time.Time { return time.Now().UTC() }}
+}
+
+// openSQLite opens or creates an SQLite database file. New files and
+// directories are readable only by the server user, as they hold the vault.
+func openSQLite(path string) (*gorm.DB, error) {
+	// Wait for locks instead of failing, and let readers work during writes.
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on", path)
+	if path == MemoryPath {
+		dsn = "file::memory:?_foreign_keys=on"
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		// SQLite gives its journal files the permissions of the database file.
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+	}
 
-	log.Println("Database connected and migrated successfully.")
-	return nil
+	db, err := gorm.Open(sqlite.Open(dsn), gormConfig())
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, one connection avoids "database is locked"
+	// errors and keeps an in-memory database alive.
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+	return db, nil
 }
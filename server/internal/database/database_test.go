@@ -1,9 +1,12 @@
 package database_test
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
 	"github.com/golangTroshin/gophkeeper/server/internal/database"
 	"github.com/golangTroshin/gophkeeper/server/internal/models"
 	"github.com/joho/godotenv"
@@ -17,15 +20,20 @@ func TestInitDB(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error loading .env file: %v", err)
 	}
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Run the database initialization
-	err = database.InitDB()
+	db, err := database.InitDB(cfg.Database)
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
+	defer database.Close(db)
 
 	// Ensure the database instance is not nil
-	if database.DB == nil {
+	if db == nil {
 		t.Fatal("Database connection is nil after InitDB")
 	}
 }
@@ -39,7 +47,7 @@ func TestMigrations(t *testing.T) {
 	}
 
 	// Run migrations
-	err = db.AutoMigrate(&models.User{}, &models.Vault{})
+	err = database.Migrate(db)
 	if err != nil {
 		t.Fatalf("Database migration failed: %v", err)
 	}
@@ -50,14 +58,169 @@ func TestMigrations(t *testing.T) {
 	}
 }
 
+// TestOpenSQLite ensures the SQLite driver creates a private database file
+func TestOpenSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "vault.db")
+	db, err := database.Open(config.Database{Driver: config.DriverSQLite, Path: path})
+	if err != nil {
+		t.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Database migration failed: %v", err)
+	}
+	database.Close(db)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected the database file to exist: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("Expected permissions 0600, got %v", perm)
+	}
+
+	if _, err := database.Open(config.Database{Driver: "oracle"}); err == nil {
+		t.Fatal("Expected an error for an unknown driver")
+	}
+}
+
 // TestInvalidDBConnection checks behavior when database connection fails
 func TestInvalidDBConnection(t *testing.T) {
-	// Set an invalid DSN to simulate failure
-	os.Setenv("DB_HOST", "invalid_host")
-	os.Setenv("DB_PORT", "invalid_port")
+	// Use an unresolvable host to simulate failure
+	cfg := config.Defaults().Database
+	cfg.Host = "invalid_host"
 
-	err := database.InitDB()
+	_, err := database.InitDB(cfg)
 	if err == nil {
 		t.Fatal("Expected an error when initializing database with invalid connection details")
 	}
 }
+
+// openTestDB opens an empty SQLite database file.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(config.Database{Driver: config.DriverSQLite, Path: filepath.Join(t.TempDir(), "vault.db")})
+	if err != nil {
+		t.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { database.Close(db) })
+	return db
+}
+
+// TestMigrateRollback ensures migrations can be applied, reverted and reapplied
+func TestMigrateRollback(t *testing.T) {
+	db := openTestDB(t)
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Database migration failed: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrating twice should do nothing: %v", err)
+	}
+
+	status, err := database.Status(db)
+	if err != nil {
+		t.Fatalf("Failed to get migration status: %v", err)
+	}
+	for _, m := range status {
+		if m.AppliedAt == nil {
+			t.Fatalf("Expected migration %d_%s to be applied", m.Version, m.Name)
+		}
+	}
+
+	if err := database.Rollback(db, 1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if db.Migrator().HasIndex(&models.Vault{}, "idx_vaults_owner_type") {
+		t.Fatal("Expected the rollback to drop the index")
+	}
+	if err := database.Rollback(db, len(status)); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if db.Migrator().HasTable(&models.User{}) {
+		t.Fatal("Expected the rollback to drop the tables")
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Database migration after rollback failed: %v", err)
+	}
+	if !db.Migrator().HasIndex(&models.Vault{}, "idx_vaults_owner_type") {
+		t.Fatal("Expected the index to be recreated")
+	}
+}
+
+// TestMigrateAutoMigrated ensures databases created by earlier releases are adopted with their data
+func TestMigrateAutoMigrated(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.Vault{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	if err := db.Create(&models.User{Login: "veteran", Password: "hash", MasterSeed: "seed"}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Database migration failed: %v", err)
+	}
+	var count int64
+	db.Model(&models.User{}).Where("login = ?", "veteran").Count(&count)
+	if count != 1 {
+		t.Fatal("Expected existing users to be kept")
+	}
+}
+
+// TestMigrateNewerSchema ensures the server refuses databases migrated by a newer release
+func TestMigrateNewerSchema(t *testing.T) {
+	db := openTestDB(t)
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Database migration failed: %v", err)
+	}
+	if err := db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', CURRENT_TIMESTAMP)").Error; err != nil {
+		t.Fatalf("Failed to record a future migration: %v", err)
+	}
+
+	if err := database.Migrate(db); !errors.Is(err, database.ErrSchemaTooNew) {
+		t.Fatalf("Expected ErrSchemaTooNew, got %v", err)
+	}
+	if err := database.Rollback(db, 1); !errors.Is(err, database.ErrSchemaTooNew) {
+		t.Fatalf("Expected ErrSchemaTooNew on rollback, got %v", err)
+	}
+}
+
+// TestMigrationsPerDialect ensures every dialect has the same migrations
+func TestMigrationsPerDialect(t *testing.T) {
+	postgres, err := database.Migrations("postgres")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	sqlite, err := database.Migrations("sqlite")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("Expected the same number of migrations, got %d and %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Fatalf("Migration %d differs: %s and %s", i, postgres[i].Name, sqlite[i].Name)
+		}
+	}
+	if _, err := database.Migrations("mysql"); err == nil {
+		t.Fatal("Expected an error for an unsupported dialect")
+	}
+}
+
+// TestInitDBMemory ensures an in-memory database keeps its data while open
+func TestInitDBMemory(t *testing.T) {
+	db, err := database.InitDB(config.Database{Driver: config.DriverSQLite, Path: database.MemoryPath})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close(db)
+
+	if err := db.Create(&models.User{Login: "ephemeral", Password: "hash", MasterSeed: "seed"}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	var count int64
+	if err := db.Model(&models.User{}).Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("Expected the user to be stored, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles holds the SQL migrations of every dialect, named
+// migrations/<dialect>/<version>_<name>.<up|down>.sql.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationName matches migration file names.
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// server release. Running against it could corrupt data.
+var ErrSchemaTooNew = errors.New("the database schema is newer than this server")
+
+// Migration is a versioned schema change with its rollback.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string // SQL applying the change
+	Down    string // SQL reverting the change
+}
+
+// MigrationStatus describes a migration known to the server.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // Nil if the migration is pending
+}
+
+// createMigrationsTable creates the table recording applied migrations.
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint PRIMARY KEY,
+    name text NOT NULL,
+    applied_at timestamp NOT NULL
+)`
+
+// appliedMigration is a row of the schema_migrations table.
+type appliedMigration struct {
+	Version   int `gorm:"primaryKey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName names the table recording applied migrations.
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrations returns the migrations of a dialect ("postgres" or "sqlite")
+// ordered by version.
+func Migrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	files, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database dialect %q", dialect)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		match := migrationName.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", file.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		sql, err := fs.ReadFile(migrationFiles, path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(sql)
+		} else {
+			m.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies all pending migrations, each in its own transaction. It
+// refuses to touch a database migrated by a newer server release.
+func Migrate(db *gorm.DB) error {
+	migrations, applied, err := load(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&appliedMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Rollback reverts the last steps applied migrations, newest first.
+func Rollback(db *gorm.DB, steps int) error {
+	migrations, applied, err := load(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&appliedMigration{Version: m.Version}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("rollback of migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		steps--
+	}
+	return nil
+}
+
+// Status lists the known migrations and when they were applied.
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, applied, err := load(db)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		status[i].Migration = m
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			status[i].AppliedAt = &appliedAt
+		}
+	}
+	return status, nil
+}
+
+// load returns the migrations of the database dialect and the applied ones
+// by version, creating the schema_migrations table if needed. It fails with
+// ErrSchemaTooNew if a migration unknown to this server was applied.
+func load(db *gorm.DB) ([]Migration, map[int]appliedMigration, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Exec(createMigrationsTable).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var rows []appliedMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+	}
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		if !known[row.Version] {
+			return nil, nil, fmt.Errorf("%w: migration %d_%s is unknown, upgrade the server", ErrSchemaTooNew, row.Version, row.Name)
+		}
+		applied[row.Version] = row
+	}
+	return migrations, applied, nil
+}
@@ -0,0 +1,19 @@
+-- Tables as created by earlier releases with GORM's AutoMigrate, so existing
+-- databases are adopted unchanged.
+CREATE TABLE IF NOT EXISTS users (
+    id serial PRIMARY KEY,
+    login text NOT NULL,
+    password text NOT NULL,
+    master_seed text NOT NULL,
+    CONSTRAINT uni_users_login UNIQUE (login)
+);
+
+CREATE TABLE IF NOT EXISTS vaults (
+    id bigserial PRIMARY KEY,
+    data bytea NOT NULL,
+    data_type bigint NOT NULL,
+    metadata text NOT NULL,
+    owner_id bigint NOT NULL,
+    modified_at timestamptz,
+    created_at timestamptz
+);
@@ -0,0 +1,2 @@
+-- Items are always listed per owner and type.
+CREATE INDEX IF NOT EXISTS idx_vaults_owner_type ON vaults (owner_id, data_type);
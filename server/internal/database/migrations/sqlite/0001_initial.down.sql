@@ -0,0 +1,2 @@
+DROP TABLE vaults;
+DROP TABLE users;
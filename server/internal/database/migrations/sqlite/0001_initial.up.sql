@@ -0,0 +1,19 @@
+-- Tables as created by earlier releases with GORM's AutoMigrate, so existing
+-- databases are adopted unchanged.
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    login text NOT NULL,
+    password text NOT NULL,
+    master_seed text NOT NULL,
+    CONSTRAINT uni_users_login UNIQUE (login)
+);
+
+CREATE TABLE IF NOT EXISTS vaults (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    data blob NOT NULL,
+    data_type integer NOT NULL,
+    metadata text NOT NULL,
+    owner_id integer NOT NULL,
+    modified_at datetime,
+    created_at datetime
+);
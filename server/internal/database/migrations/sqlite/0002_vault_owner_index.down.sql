@@ -0,0 +1 @@
+DROP INDEX idx_vaults_owner_type;
@@ -2,29 +2,111 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
 	"github.com/golangTroshin/gophkeeper/server/internal/models"
 	"github.com/golangTroshin/gophkeeper/server/internal/repository"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// DefaultMaxItemSize is the item size limit used when the configured limit
+// is zero. It keeps requests below the 4 MiB default gRPC message size.
+const DefaultMaxItemSize = 3 << 20
+
+// Clock returns the current time. Tests pass a fixed clock.
+type Clock func() time.Time
+
 // GophKeeperServer implements the GophKeeper gRPC service.
 type GophKeeperServer struct {
 	pb.UnimplementedGophKeeperServiceServer
-	Repo repository.Repository
+	repo         repository.Repository
+	maxItemSize  int    // Maximum size of an item's data and metadata in bytes
+	maxUserBytes int64  // Storage quota per user, unlimited if 0
+	maxUserItems int    // Item quota per user, unlimited if 0
+	tokens       Tokens // Session token settings
+	logger       *slog.Logger
+}
+
+// NewServer assembles the service from the configuration and its
+// dependencies, so it can be registered on any gRPC server. A nil clock
+// uses time.Now and a nil logger slog.Default.
+func NewServer(cfg *config.Config, repo repository.Repository, clock Clock, logger *slog.Logger) *GophKeeperServer {
+	if clock == nil {
+		clock = time.Now
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	maxItemSize := cfg.Limits.MaxItemSize
+	if maxItemSize <= 0 {
+		maxItemSize = DefaultMaxItemSize
+	}
+	return &GophKeeperServer{
+		repo:         repo,
+		maxItemSize:  maxItemSize,
+		maxUserBytes: cfg.Limits.MaxUserBytes,
+		maxUserItems: cfg.Limits.MaxUserItems,
+		tokens:       Tokens{Secret: []byte(cfg.JWT.Secret), TTL: cfg.JWT.TTL, Now: clock},
+		logger:       logger,
+	}
 }
 
-var jwtSecret = []byte("$2a$10$1OTcy6ZovRCBv3wRLr3UseAPZgXTgGewGGTO/fctDauTR/QrCSnKu")
+// DefaultTokenTTL is the token lifetime used when Tokens.TTL is zero.
+const DefaultTokenTTL = 24 * time.Hour
+
+// Tokens signs and verifies session tokens.
+type Tokens struct {
+	Secret []byte        // HMAC key, a random key per process if empty
+	TTL    time.Duration // Lifetime of issued tokens
+	Now    Clock         // Current time, time.Now if nil
+}
+
+// processSecret signs tokens when no secret is configured. Tokens signed
+// with it become invalid when the server restarts.
+var processSecret = sync.OnceValue(func() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate the token secret: %v", err))
+	}
+	return secret
+})
+
+// secret returns the signing key.
+func (t Tokens) secret() []byte {
+	if len(t.Secret) > 0 {
+		return t.Secret
+	}
+	return processSecret()
+}
+
+// now returns the current time of the token clock.
+func (t Tokens) now() time.Time {
+	if t.Now != nil {
+		return t.Now()
+	}
+	return time.Now()
+}
 
 // VerifyToken verifies the validity of a JWT token and extracts the user ID.
-func VerifyToken(tokenString string) (uint, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+func (t Tokens) VerifyToken(tokenString string) (uint, error) {
+	// Expiry is checked below against the token clock.
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return t.secret(), nil
 	})
 
 	if err != nil || !token.Valid {
@@ -32,7 +114,7 @@ func VerifyToken(tokenString string) (uint, error) {
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	if !ok || !claims.VerifyExpiresAt(t.now().Unix(), true) {
 		return 0, fmt.Errorf("invalid token claims")
 	}
 
@@ -45,25 +127,21 @@ func VerifyToken(tokenString string) (uint, error) {
 }
 
 // GenerateJWT generates a JWT token for a given user ID.
-func GenerateJWT(userID uint) (string, error) {
+func (t Tokens) GenerateJWT(userID uint) (string, error) {
+	ttl := t.TTL
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"exp":     t.now().Add(ttl).Unix(),
 	})
-	return token.SignedString(jwtSecret)
+	return token.SignedString(t.secret())
 }
 
 // RegisterUser registers a new user, hashes the password, and stores the master seed.
 func (s *GophKeeperServer) RegisterUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.RegisterUserResponse, error) {
-	log.Printf("Registering user: %s", req.Username)
-
-	exists, err := s.Repo.UserExists(req.Username)
-	if err != nil {
-		return &pb.RegisterUserResponse{Success: false, Message: "Database error"}, err
-	}
-	if exists {
-		return &pb.RegisterUserResponse{Success: false, Message: "User already exists"}, nil
-	}
+	s.logger.Info("Registering user", "username", req.Username)
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -75,11 +153,25 @@ func (s *GophKeeperServer) RegisterUser(ctx context.Context, req *pb.RegisterUse
 		Password:   string(hashedPassword),
 		MasterSeed: req.Seed,
 	}
-	if err := s.Repo.CreateUser(&user); err != nil {
-		return &pb.RegisterUserResponse{Success: false, Message: "Failed to register user"}, err
+	errUserExists := errors.New("user already exists")
+	err = s.repo.WithTx(ctx, func(tx repository.Repository) error {
+		exists, err := tx.UserExists(ctx, req.Username)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errUserExists
+		}
+		return tx.CreateUser(ctx, &user)
+	})
+	if errors.Is(err, errUserExists) {
+		return &pb.RegisterUserResponse{Success: false, Message: "User already exists"}, nil
+	}
+	if err != nil {
+		return &pb.RegisterUserResponse{Success: false, Message: "Failed to register user"}, repoError(ctx, err)
 	}
 
-	token, err := GenerateJWT(uint(user.ID))
+	token, err := s.tokens.GenerateJWT(uint(user.ID))
 	if err != nil {
 		return &pb.RegisterUserResponse{Success: false, Message: "Failed to generate token"}, err
 	}
@@ -89,18 +181,18 @@ func (s *GophKeeperServer) RegisterUser(ctx context.Context, req *pb.RegisterUse
 
 // AuthenticateUser verifies user credentials and returns a JWT token.
 func (s *GophKeeperServer) AuthenticateUser(ctx context.Context, req *pb.AuthenticateUserRequest) (*pb.AuthenticateUserResponse, error) {
-	log.Printf("Authenticating user: %s", req.Username)
+	s.logger.Info("Authenticating user", "username", req.Username)
 
-	user, err := s.Repo.GetUserByLogin(req.Username)
+	user, err := s.repo.GetUserByLogin(ctx, req.Username)
 	if err != nil {
-		return &pb.AuthenticateUserResponse{Success: false, Message: "Invalid username or password"}, nil
+		return &pb.AuthenticateUserResponse{Success: false, Message: "Invalid username or password"}, repoError(ctx, nil)
 	}
 
 	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
 		return &pb.AuthenticateUserResponse{Success: false, Message: "Invalid username or password"}, nil
 	}
 
-	token, err := GenerateJWT(uint(user.ID))
+	token, err := s.tokens.GenerateJWT(uint(user.ID))
 	if err != nil {
 		return &pb.AuthenticateUserResponse{Success: false, Message: "Failed to generate token"}, err
 	}
@@ -110,59 +202,181 @@ func (s *GophKeeperServer) AuthenticateUser(ctx context.Context, req *pb.Authent
 
 // StoreData saves encrypted user data into the database.
 func (s *GophKeeperServer) StoreData(ctx context.Context, req *pb.StoreDataRequest) (*pb.StoreDataResponse, error) {
-	userID, err := VerifyToken(req.Token)
+	userID, err := s.tokens.VerifyToken(req.Token)
 	if err != nil {
 		return &pb.StoreDataResponse{Success: false, Message: "Unauthorized"}, nil
 	}
 
+	if size := len(req.Data) + len(req.Metadata); size > s.maxItemSize {
+		message := fmt.Sprintf("Item is %d bytes, the limit is %d bytes", size, s.maxItemSize)
+		return &pb.StoreDataResponse{Success: false, Message: message}, status.Error(codes.InvalidArgument, message)
+	}
+
 	entry := models.Vault{
 		OwnerID:  uint(userID),
 		DataType: req.DataType,
 		Data:     req.Data,
 		Metadata: req.Metadata,
 	}
-	if err := s.Repo.StoreData(&entry); err != nil {
-		return &pb.StoreDataResponse{Success: false, Message: "Failed to store data"}, err
+	var exceeded string
+	err = s.repo.WithTx(ctx, func(tx repository.Repository) error {
+		usage, err := tx.Usage(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if exceeded = s.quotaExceeded(usage, int64(len(req.Data)+len(req.Metadata))); exceeded != "" {
+			return nil
+		}
+		return tx.StoreData(ctx, &entry)
+	})
+	if err != nil {
+		return &pb.StoreDataResponse{Success: false, Message: "Failed to store data"}, repoError(ctx, err)
+	}
+	if exceeded != "" {
+		return &pb.StoreDataResponse{Success: false, Message: exceeded}, status.Error(codes.ResourceExhausted, exceeded)
 	}
 
 	return &pb.StoreDataResponse{Success: true, Message: "Data stored successfully"}, nil
 }
 
-// RetrieveData retrieves encrypted user data based on data type.
+// quotaExceeded describes the quota that storing size more bytes would
+// exceed, or returns an empty string.
+func (s *GophKeeperServer) quotaExceeded(usage repository.Usage, size int64) string {
+	if s.maxUserItems > 0 && usage.Items >= int64(s.maxUserItems) {
+		return fmt.Sprintf("Item quota reached, %d of %d items stored", usage.Items, s.maxUserItems)
+	}
+	if s.maxUserBytes > 0 && usage.Bytes+size > s.maxUserBytes {
+		return fmt.Sprintf("Storage quota exceeded, %d of %d bytes used and the item needs %d", usage.Bytes, s.maxUserBytes, size)
+	}
+	return ""
+}
+
+// GetUsage reports the storage used by the user and the limits that apply.
+func (s *GophKeeperServer) GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error) {
+	userID, err := s.tokens.VerifyToken(req.Token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	usage, err := s.repo.Usage(ctx, userID)
+	if err != nil {
+		return nil, repoError(ctx, err)
+	}
+	return &pb.GetUsageResponse{
+		Items:       usage.Items,
+		Bytes:       usage.Bytes,
+		MaxItems:    int64(s.maxUserItems),
+		MaxBytes:    s.maxUserBytes,
+		MaxItemSize: int64(s.maxItemSize),
+	}, nil
+}
+
+// RetrieveData retrieves a page of encrypted user data selected by type,
+// modification time and sort order.
 func (s *GophKeeperServer) RetrieveData(ctx context.Context, req *pb.RetrieveDataRequest) (*pb.RetrieveDataResponse, error) {
-	userID, err := VerifyToken(req.Token)
+	userID, err := s.tokens.VerifyToken(req.Token)
 	if err != nil {
-		return &pb.RetrieveDataResponse{}, err
+		return &pb.RetrieveDataResponse{}, status.Error(codes.Unauthenticated, "Unauthorized")
 	}
 
-	entries, err := s.Repo.RetrieveData(userID, req.Filter)
+	query, err := retrieveQuery(req)
+	if err != nil {
+		return &pb.RetrieveDataResponse{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	entries, next, err := s.fetchPage(ctx, userID, query)
 	if err != nil {
 		return &pb.RetrieveDataResponse{}, err
 	}
 
-	var items []*pb.DataItem
+	res := &pb.RetrieveDataResponse{NextPageToken: next}
 	for _, entry := range entries {
-		items = append(items, &pb.DataItem{
-			DataType: entry.DataType,
-			Metadata: entry.Metadata,
-			Data:     entry.Data,
+		res.Items = append(res.Items, dataItem(entry))
+	}
+
+	return res, nil
+}
+
+// ListItems lists the headers of a user's items without their data, so
+// clients can show lists without downloading every item.
+func (s *GophKeeperServer) ListItems(ctx context.Context, req *pb.ListItemsRequest) (*pb.ListItemsResponse, error) {
+	userID, err := s.tokens.VerifyToken(req.Token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	query, err := listQuery(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	entries, next, err := s.fetchPage(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListItemsResponse{NextPageToken: next}
+	for _, entry := range entries {
+		res.Items = append(res.Items, &pb.ItemHeader{
+			Id:         uint64(entry.ID),
+			DataType:   entry.DataType,
+			Metadata:   entry.Metadata,
+			Size:       entry.Size,
+			CreatedAt:  timestamppb.New(entry.CreatedAt),
+			ModifiedAt: timestamppb.New(entry.ModifiedAt),
 		})
 	}
+	return res, nil
+}
+
+// GetItem returns one item of the user with its data.
+func (s *GophKeeperServer) GetItem(ctx context.Context, req *pb.GetItemRequest) (*pb.GetItemResponse, error) {
+	userID, err := s.tokens.VerifyToken(req.Token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
 
-	return &pb.RetrieveDataResponse{Items: items}, nil
+	entry, err := s.repo.GetData(ctx, userID, uint(req.Id))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "Item %d not found", req.Id)
+	}
+	if err != nil {
+		return nil, repoError(ctx, err)
+	}
+	return &pb.GetItemResponse{Item: dataItem(*entry)}, nil
+}
+
+// dataItem converts a stored item into its gRPC form.
+func dataItem(entry models.Vault) *pb.DataItem {
+	return &pb.DataItem{
+		Id:         uint64(entry.ID),
+		DataType:   entry.DataType,
+		Metadata:   entry.Metadata,
+		Data:       entry.Data,
+		CreatedAt:  timestamppb.New(entry.CreatedAt),
+		ModifiedAt: timestamppb.New(entry.ModifiedAt),
+	}
 }
 
 // MasterSeedRetrieve retrieves the encrypted master seed for a user.
 func (s *GophKeeperServer) MasterSeedRetrieve(ctx context.Context, req *pb.MasterSeedRetrieveRequest) (*pb.MasterSeedRetrieveResponse, error) {
-	userID, err := VerifyToken(req.Token)
+	userID, err := s.tokens.VerifyToken(req.Token)
 	if err != nil {
 		return &pb.MasterSeedRetrieveResponse{Success: false, Message: "Unauthorized"}, nil
 	}
 
-	masterSeed, err := s.Repo.GetMasterSeed(userID)
+	masterSeed, err := s.repo.GetMasterSeed(ctx, userID)
 	if err != nil {
-		return &pb.MasterSeedRetrieveResponse{Success: false, Message: "User not found"}, nil
+		return &pb.MasterSeedRetrieveResponse{Success: false, Message: "User not found"}, repoError(ctx, nil)
 	}
 
 	return &pb.MasterSeedRetrieveResponse{Success: true, MasterSeed: masterSeed, Message: "Master seed retrieved successfully"}, nil
 }
+
+// repoError returns the error of a failed repository call, reporting calls
+// cancelled by the client or past their deadline with the matching gRPC
+// code.
+func repoError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+	return err
+}
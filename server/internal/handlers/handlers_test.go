@@ -2,36 +2,35 @@ package handlers_test
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"testing"
+	"time"
 
 	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
+	"github.com/golangTroshin/gophkeeper/server/internal/database"
 	"github.com/golangTroshin/gophkeeper/server/internal/handlers"
-	"github.com/golangTroshin/gophkeeper/server/internal/models"
 	"github.com/golangTroshin/gophkeeper/server/internal/repository"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var testRepo repository.Repository
 var testServer *handlers.GophKeeperServer
 
-// setupTestDB initializes an in-memory SQLite database for testing
+// setupTestDB initializes an in-memory SQLite database and a server using it for testing
 func setupTestDB(t *testing.T) {
-	var err error
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db, err := database.InitDB(config.Database{Driver: config.DriverSQLite, Path: database.MemoryPath})
 	if err != nil {
 		t.Fatalf("Failed to create in-memory database: %v", err)
 	}
-
-	// Run migrations
-	err = db.AutoMigrate(&models.User{}, &models.Vault{})
-	if err != nil {
-		t.Fatalf("Database migration failed: %v", err)
-	}
+	t.Cleanup(func() { database.Close(db) })
 
 	// Initialize repository and server with test DB
+	cfg := config.Defaults()
 	testRepo = repository.NewRepository(db)
-	testServer = &handlers.GophKeeperServer{Repo: testRepo}
+	testServer = handlers.NewServer(&cfg, testRepo, nil, nil)
 }
 
 // TestRegisterUser ensures a user is created successfully
@@ -82,15 +81,17 @@ func TestAuthenticateUser(t *testing.T) {
 	}
 }
 
+// TestVerifyToken ensures tokens are only accepted with the secret that signed them
 func TestVerifyToken(t *testing.T) {
-	token, err := handlers.GenerateJWT(1)
+	tokens := handlers.Tokens{Secret: []byte("0123456789abcdef0123456789abcdef")}
+	token, err := tokens.GenerateJWT(1)
 	if err != nil {
 		t.Fatalf("Failed to generate JWT: %v", err)
 	}
 
 	t.Logf("Generated Token: %s", token) // Log the token for debugging
 
-	userID, err := handlers.VerifyToken(token)
+	userID, err := tokens.VerifyToken(token)
 	if err != nil {
 		t.Fatalf("Failed to verify token: %v", err)
 	}
@@ -98,6 +99,10 @@ func TestVerifyToken(t *testing.T) {
 	if userID != 1 {
 		t.Fatalf("Expected user ID 1, got %d", userID)
 	}
+
+	if _, err := (handlers.Tokens{}).VerifyToken(token); err == nil {
+		t.Fatalf("Expected tokens signed with another secret to be rejected")
+	}
 }
 
 // TestStoreAndRetrieveData checks if encrypted data can be stored and retrieved
@@ -143,6 +148,47 @@ func TestStoreAndRetrieveData(t *testing.T) {
 	if len(retrieveRes.Items) == 0 {
 		t.Fatal("Expected retrieved data, got none")
 	}
+
+	if retrieveRes.Items[0].Id == 0 {
+		t.Fatal("Expected retrieved item to have an ID")
+	}
+}
+
+// TestStoreDataSizeLimit ensures items larger than the configured limit are rejected
+func TestStoreDataSizeLimit(t *testing.T) {
+	setupTestDB(t)
+	cfg := config.Defaults()
+	cfg.Limits.MaxItemSize = 64
+	testServer = handlers.NewServer(&cfg, testRepo, nil, nil)
+
+	regRes, _ := testServer.RegisterUser(context.Background(), &pb.RegisterUserRequest{
+		Username: "limituser",
+		Password: "limitpass",
+		Seed:     "limitseed",
+	})
+
+	storeRes, err := testServer.StoreData(context.Background(), &pb.StoreDataRequest{
+		Token:    regRes.Token,
+		DataType: pb.DataType_TEXT,
+		Metadata: "Large note",
+		Data:     make([]byte, 64),
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got: %v", err)
+	}
+	if storeRes.Success {
+		t.Fatal("Expected oversized item to be rejected")
+	}
+
+	storeRes, err = testServer.StoreData(context.Background(), &pb.StoreDataRequest{
+		Token:    regRes.Token,
+		DataType: pb.DataType_TEXT,
+		Metadata: "Small note",
+		Data:     make([]byte, 32),
+	})
+	if err != nil || !storeRes.Success {
+		t.Fatalf("Expected item within the limit to be stored, got: %v", err)
+	}
 }
 
 // TestMasterSeedRetrieve ensures the master seed is correctly retrieved
@@ -168,3 +214,239 @@ func TestMasterSeedRetrieve(t *testing.T) {
 		t.Fatalf("Master seed retrieval failed: expected 'test-master-seed', got '%s'", seedRes.MasterSeed)
 	}
 }
+
+// TestTokenExpiry ensures tokens are rejected once their lifetime has passed on the server clock
+func TestTokenExpiry(t *testing.T) {
+	setupTestDB(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	cfg := config.Defaults()
+	cfg.JWT.TTL = time.Hour
+	testServer = handlers.NewServer(&cfg, testRepo, func() time.Time { return now }, nil)
+
+	regRes, err := testServer.RegisterUser(context.Background(), &pb.RegisterUserRequest{
+		Username: "clockuser",
+		Password: "clockpass",
+		Seed:     "clockseed",
+	})
+	if err != nil || !regRes.Success {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+
+	now = now.Add(59 * time.Minute)
+	res, _ := testServer.MasterSeedRetrieve(context.Background(), &pb.MasterSeedRetrieveRequest{Token: regRes.Token})
+	if !res.Success {
+		t.Fatalf("Expected the token to be valid before it expires, got: %s", res.Message)
+	}
+
+	now = now.Add(2 * time.Minute)
+	res, _ = testServer.MasterSeedRetrieve(context.Background(), &pb.MasterSeedRetrieveRequest{Token: regRes.Token})
+	if res.Success {
+		t.Fatal("Expected the expired token to be rejected")
+	}
+}
+
+// TestCancelledRequest ensures cancelled and timed-out calls stop with the matching gRPC code
+func TestCancelledRequest(t *testing.T) {
+	setupTestDB(t)
+	regRes, _ := testServer.RegisterUser(context.Background(), &pb.RegisterUserRequest{
+		Username: "cancelluser",
+		Password: "cancelpass",
+		Seed:     "cancelseed",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := testServer.RetrieveData(ctx, &pb.RetrieveDataRequest{Token: regRes.Token, Filter: pb.DataType_TEXT})
+	if status.Code(err) != codes.Canceled {
+		t.Fatalf("Expected Canceled, got %v", err)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = testServer.StoreData(ctx, &pb.StoreDataRequest{Token: regRes.Token, DataType: pb.DataType_TEXT, Data: []byte("late")})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("Expected DeadlineExceeded, got %v", err)
+	}
+
+	res, err := testServer.RegisterUser(ctx, &pb.RegisterUserRequest{Username: "lateuser", Password: "latepass", Seed: "lateseed"})
+	if status.Code(err) != codes.DeadlineExceeded || res.Success {
+		t.Fatalf("Expected the registration to time out, got %v", err)
+	}
+}
+
+// TestRetrieveDataPaging ensures large results are returned in pages that can be followed to the end
+func TestRetrieveDataPaging(t *testing.T) {
+	setupTestDB(t)
+	regRes, _ := testServer.RegisterUser(context.Background(), &pb.RegisterUserRequest{
+		Username: "pageuser",
+		Password: "pagepass",
+		Seed:     "pageseed",
+	})
+	for i := 0; i < 5; i++ {
+		_, err := testServer.StoreData(context.Background(), &pb.StoreDataRequest{
+			Token:    regRes.Token,
+			DataType: pb.DataType_TEXT,
+			Metadata: fmt.Sprintf("note %d", i),
+			Data:     []byte("secret"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store data: %v", err)
+		}
+	}
+
+	var seen []string
+	req := &pb.RetrieveDataRequest{Token: regRes.Token, Filter: pb.DataType_TEXT, PageSize: 2, Sort: pb.SortOrder_CREATED_DESC, HeadersOnly: true}
+	for pages := 1; ; pages++ {
+		res, err := testServer.RetrieveData(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Failed to retrieve page %d: %v", pages, err)
+		}
+		for _, item := range res.Items {
+			if item.Data != nil || item.CreatedAt == nil {
+				t.Fatalf("Expected a header with timestamps, got %+v", item)
+			}
+			seen = append(seen, item.Metadata)
+		}
+		if res.NextPageToken == "" {
+			if pages != 3 {
+				t.Fatalf("Expected 3 pages, got %d", pages)
+			}
+			break
+		}
+		req.PageToken = res.NextPageToken
+	}
+	if want := []string{"note 4", "note 3", "note 2", "note 1", "note 0"}; !slices.Equal(seen, want) {
+		t.Fatalf("Expected %v, got %v", want, seen)
+	}
+
+	req.Sort = pb.SortOrder_MODIFIED_ASC
+	if _, err := testServer.RetrieveData(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument for a token of another sort order, got %v", err)
+	}
+	req.PageToken = "garbage"
+	if _, err := testServer.RetrieveData(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument for an invalid token, got %v", err)
+	}
+}
+
+// TestListAndGetItem ensures items are listed as headers and fetched one at a time by their owner
+func TestListAndGetItem(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	owner, _ := testServer.RegisterUser(ctx, &pb.RegisterUserRequest{Username: "owner", Password: "ownerpass", Seed: "seed"})
+	other, _ := testServer.RegisterUser(ctx, &pb.RegisterUserRequest{Username: "other", Password: "otherpass", Seed: "seed"})
+
+	for _, item := range []*pb.StoreDataRequest{
+		{Token: owner.Token, DataType: pb.DataType_CREDENTIALS, Metadata: "Mail", Data: []byte("login")},
+		{Token: owner.Token, DataType: pb.DataType_BINARY, Metadata: "Photo", Data: make([]byte, 1000)},
+	} {
+		if _, err := testServer.StoreData(ctx, item); err != nil {
+			t.Fatalf("Failed to store data: %v", err)
+		}
+	}
+
+	list, err := testServer.ListItems(ctx, &pb.ListItemsRequest{Token: owner.Token, Sort: pb.SortOrder_CREATED_DESC})
+	if err != nil {
+		t.Fatalf("Failed to list items: %v", err)
+	}
+	if len(list.Items) != 2 || list.NextPageToken != "" {
+		t.Fatalf("Expected both items on one page, got %d", len(list.Items))
+	}
+	photo := list.Items[0]
+	if photo.Metadata != "Photo" || photo.Size != 1000 || photo.DataType != pb.DataType_BINARY {
+		t.Fatalf("Unexpected header %+v", photo)
+	}
+
+	res, err := testServer.GetItem(ctx, &pb.GetItemRequest{Token: owner.Token, Id: photo.Id})
+	if err != nil {
+		t.Fatalf("Failed to get item: %v", err)
+	}
+	if len(res.Item.Data) != 1000 || res.Item.Metadata != "Photo" {
+		t.Fatalf("Expected the full item, got %d bytes", len(res.Item.Data))
+	}
+
+	if _, err := testServer.GetItem(ctx, &pb.GetItemRequest{Token: other.Token, Id: photo.Id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound for another user's item, got %v", err)
+	}
+	if _, err := testServer.ListItems(ctx, &pb.ListItemsRequest{Token: "invalid"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated, got %v", err)
+	}
+	if _, err := testServer.RetrieveData(ctx, &pb.RetrieveDataRequest{Token: "invalid"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated, got %v", err)
+	}
+}
+
+// TestRetrieveDataAllTypes ensures all_types returns every type, and requests of older clients without a filter only credentials
+func TestRetrieveDataAllTypes(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	regRes, _ := testServer.RegisterUser(ctx, &pb.RegisterUserRequest{Username: "alltypes", Password: "allpass", Seed: "seed"})
+	for _, dataType := range []pb.DataType{pb.DataType_TEXT, pb.DataType_CARD, pb.DataType_OTP} {
+		if _, err := testServer.StoreData(ctx, &pb.StoreDataRequest{Token: regRes.Token, DataType: dataType, Data: []byte("x")}); err != nil {
+			t.Fatalf("Failed to store data: %v", err)
+		}
+	}
+
+	res, err := testServer.RetrieveData(ctx, &pb.RetrieveDataRequest{Token: regRes.Token, AllTypes: true})
+	if err != nil {
+		t.Fatalf("Failed to retrieve data: %v", err)
+	}
+	if len(res.Items) != 3 {
+		t.Fatalf("Expected items of every type, got %d", len(res.Items))
+	}
+
+	// Filter CREDENTIALS is the zero value and not sent on the wire.
+	res, err = testServer.RetrieveData(ctx, &pb.RetrieveDataRequest{Token: regRes.Token})
+	if err != nil {
+		t.Fatalf("Failed to retrieve data: %v", err)
+	}
+	if len(res.Items) != 0 {
+		t.Fatalf("Expected no credentials, got %d items", len(res.Items))
+	}
+
+	res, err = testServer.RetrieveData(ctx, &pb.RetrieveDataRequest{Token: regRes.Token, Types: []pb.DataType{pb.DataType_CARD, pb.DataType_OTP}})
+	if err != nil {
+		t.Fatalf("Failed to retrieve data: %v", err)
+	}
+	if len(res.Items) != 2 {
+		t.Fatalf("Expected cards and OTP items, got %d", len(res.Items))
+	}
+}
+
+// TestQuotas ensures users cannot exceed their item and storage quotas and can see their usage
+func TestQuotas(t *testing.T) {
+	setupTestDB(t)
+	cfg := config.Defaults()
+	cfg.Limits.MaxUserItems = 2
+	cfg.Limits.MaxUserBytes = 150
+	testServer = handlers.NewServer(&cfg, testRepo, nil, nil)
+	ctx := context.Background()
+	regRes, _ := testServer.RegisterUser(ctx, &pb.RegisterUserRequest{Username: "quotauser", Password: "quotapass", Seed: "seed"})
+
+	store := func(size int, metadata string) (*pb.StoreDataResponse, error) {
+		return testServer.StoreData(ctx, &pb.StoreDataRequest{Token: regRes.Token, DataType: pb.DataType_BINARY, Data: make([]byte, size), Metadata: metadata})
+	}
+	if res, err := store(90, "ten bytes!"); err != nil || !res.Success {
+		t.Fatalf("Failed to store data: %v", err)
+	}
+	if res, err := store(45, "ten bytes!"); status.Code(err) != codes.ResourceExhausted || res.Success {
+		t.Fatalf("Expected ResourceExhausted for the storage quota counting metadata, got %v", err)
+	}
+	if res, err := store(40, "ten bytes!"); err != nil || !res.Success {
+		t.Fatalf("Expected an item filling the quota to be stored: %v", err)
+	}
+	if _, err := store(0, ""); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted for the item quota, got %v", err)
+	}
+
+	usage, err := testServer.GetUsage(ctx, &pb.GetUsageRequest{Token: regRes.Token})
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.Items != 2 || usage.Bytes != 150 || usage.MaxItems != 2 || usage.MaxBytes != 150 || usage.MaxItemSize != int64(cfg.Limits.MaxItemSize) {
+		t.Fatalf("Unexpected usage %+v", usage)
+	}
+	if _, err := testServer.GetUsage(ctx, &pb.GetUsageRequest{Token: "invalid"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated, got %v", err)
+	}
+}
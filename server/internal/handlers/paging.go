@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/golangTroshin/gophkeeper/server/internal/models"
+	"github.com/golangTroshin/gophkeeper/server/internal/repository"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Page sizes of RetrieveData.
+const (
+	DefaultPageSize = 100  // Items per page if the request sets none
+	MaxPageSize     = 1000 // Larger requested pages are reduced to this size
+)
+
+// pageToken is the decoded form of a page token: the position of the last
+// item of the previous page in the sort order it was requested with.
+type pageToken struct {
+	Sort pb.SortOrder `json:"s"`
+	Time int64        `json:"t"` // Unix nanoseconds
+	ID   uint         `json:"i"`
+}
+
+// retrieveQuery converts a RetrieveData request into a repository query of
+// one page. Without types, all_types selects every type and otherwise filter
+// applies, so clients predating types still get credentials for filter 0,
+// which proto3 does not send.
+func retrieveQuery(req *pb.RetrieveDataRequest) (repository.Query, error) {
+	types := req.Types
+	if len(types) == 0 && !req.AllTypes {
+		types = []pb.DataType{req.Filter}
+	}
+	query, err := pageQuery(types, req.ModifiedSince, req.Sort, req.PageSize, req.PageToken)
+	query.HeadersOnly = req.HeadersOnly
+	return query, err
+}
+
+// listQuery converts a ListItems request into a repository query of one
+// page of headers.
+func listQuery(req *pb.ListItemsRequest) (repository.Query, error) {
+	query, err := pageQuery(req.Types, req.ModifiedSince, req.Sort, req.PageSize, req.PageToken)
+	query.HeadersOnly = true
+	return query, err
+}
+
+// pageQuery builds the repository query of one page from the paging
+// fields shared by the listing requests.
+func pageQuery(types []pb.DataType, modifiedSince *timestamppb.Timestamp, sort pb.SortOrder, pageSize int32, token string) (repository.Query, error) {
+	query := repository.Query{Types: types, Sort: sort, Limit: int(pageSize)}
+	if _, ok := pb.SortOrder_name[int32(sort)]; !ok {
+		return query, fmt.Errorf("unknown sort order %d", sort)
+	}
+
+	switch {
+	case query.Limit < 0:
+		return query, fmt.Errorf("invalid page size %d", pageSize)
+	case query.Limit == 0:
+		query.Limit = DefaultPageSize
+	case query.Limit > MaxPageSize:
+		query.Limit = MaxPageSize
+	}
+
+	if modifiedSince != nil {
+		if err := modifiedSince.CheckValid(); err != nil {
+			return query, fmt.Errorf("invalid modified_since: %w", err)
+		}
+		query.ModifiedSince = modifiedSince.AsTime()
+	}
+
+	if token != "" {
+		after, err := decodePageToken(token, sort)
+		if err != nil {
+			return query, err
+		}
+		query.After = &after
+	}
+	return query, nil
+}
+
+// fetchPage runs query, which asks for one page, and returns the page with
+// the token of the next one, empty on the last page.
+func (s *GophKeeperServer) fetchPage(ctx context.Context, userID uint, query repository.Query) ([]models.Vault, string, error) {
+	pageSize := query.Limit
+	query.Limit++ // One more item tells whether another page follows
+
+	entries, err := s.repo.RetrieveData(ctx, userID, query)
+	if err != nil {
+		return nil, "", repoError(ctx, err)
+	}
+	if len(entries) <= pageSize {
+		return entries, "", nil
+	}
+	entries = entries[:pageSize]
+	return entries, encodePageToken(query.Sort, repository.CursorOf(entries[pageSize-1], query.Sort)), nil
+}
+
+// encodePageToken returns the opaque token of the page after cursor.
+func encodePageToken(sort pb.SortOrder, cursor repository.Cursor) string {
+	data, _ := json.Marshal(pageToken{Sort: sort, Time: cursor.Time.UnixNano(), ID: cursor.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageToken returns the cursor of a page token, which must have been
+// issued for the same sort order.
+func decodePageToken(token string, sort pb.SortOrder) (repository.Cursor, error) {
+	var decoded pageToken
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(data, &decoded) != nil {
+		return repository.Cursor{}, errors.New("invalid page token")
+	}
+	if decoded.Sort != sort {
+		return repository.Cursor{}, errors.New("the page token belongs to another sort order")
+	}
+	return repository.Cursor{Time: time.Unix(0, decoded.Time).UTC(), ID: decoded.ID}, nil
+}
@@ -24,4 +24,5 @@ type Vault struct {
 	OwnerID    uint        `gorm:"not null"`       // ID of the user who owns this data
 	ModifiedAt time.Time   `gorm:"autoUpdateTime"` // Timestamp of last modification
 	CreatedAt  time.Time   `gorm:"autoCreateTime"` // Timestamp of when the data was created
+	Size       int64       `gorm:"->;-:migration"` // Size of Data in bytes, only read with headers
 }
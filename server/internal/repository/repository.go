@@ -3,20 +3,84 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	pb "github.com/golangTroshin/gophkeeper/grpc"
 	"github.com/golangTroshin/gophkeeper/server/internal/models"
 
 	"gorm.io/gorm"
 )
 
-// Repository defines the database operations for GophKeeper.
+// Repository defines the database operations for GophKeeper. Every
+// operation stops when its context is cancelled or its deadline passes.
 type Repository interface {
-	UserExists(username string) (bool, error)
-	CreateUser(user *models.User) error
-	GetUserByLogin(username string) (*models.User, error)
-	StoreData(entry *models.Vault) error
-	RetrieveData(userID uint, dataType pb.DataType) ([]models.Vault, error)
-	GetMasterSeed(userID uint) (string, error)
+	UserExists(ctx context.Context, username string) (bool, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByLogin(ctx context.Context, username string) (*models.User, error)
+	StoreData(ctx context.Context, entry *models.Vault) error
+	RetrieveData(ctx context.Context, userID uint, query Query) ([]models.Vault, error)
+	GetData(ctx context.Context, userID uint, id uint) (*models.Vault, error)
+	GetMasterSeed(ctx context.Context, userID uint) (string, error)
+	Usage(ctx context.Context, userID uint) (Usage, error)
+
+	// WithTx runs fn in a transaction, committed if fn returns nil and
+	// rolled back otherwise. The Repository passed to fn belongs to the
+	// transaction and must not be used after fn returns.
+	WithTx(ctx context.Context, fn func(Repository) error) error
+}
+
+// Query selects, orders and limits the items returned by RetrieveData.
+type Query struct {
+	Types         []pb.DataType // Types to return, all if empty
+	ModifiedSince time.Time     // Only items modified at or after this time, if set
+	Sort          pb.SortOrder
+	After         *Cursor // Start after this position of the sort order
+	Limit         int     // Maximum number of items, unlimited if zero
+	HeadersOnly   bool    // Leave Data empty and set Size instead
+}
+
+// Usage is the storage used by a user.
+type Usage struct {
+	Items int64 // Number of stored items
+	Bytes int64 // Total size of the items' data and metadata
+}
+
+// ErrNotFound is returned for items that do not exist or belong to another user.
+var ErrNotFound = errors.New("item not found")
+
+// headerColumns are the columns read for item headers.
+const headerColumns = "id, data_type, metadata, owner_id, modified_at, created_at, LENGTH(data) AS size"
+
+// Cursor is the position of an item in a sort order.
+type Cursor struct {
+	Time time.Time // Creation or modification time, depending on the sort order
+	ID   uint
+}
+
+// CursorOf returns the position of entry in the sort order.
+func CursorOf(entry models.Vault, sort pb.SortOrder) Cursor {
+	column, _ := sortColumn(sort)
+	if column == "modified_at" {
+		return Cursor{Time: entry.ModifiedAt, ID: entry.ID}
+	}
+	return Cursor{Time: entry.CreatedAt, ID: entry.ID}
+}
+
+// sortColumn returns the column and direction of a sort order.
+func sortColumn(sort pb.SortOrder) (column string, desc bool) {
+	switch sort {
+	case pb.SortOrder_CREATED_DESC:
+		return "created_at", true
+	case pb.SortOrder_MODIFIED_ASC:
+		return "modified_at", false
+	case pb.SortOrder_MODIFIED_DESC:
+		return "modified_at", true
+	default:
+		return "created_at", false
+	}
 }
 
 // repositoryImpl is the concrete implementation of Repository using GORM.
@@ -30,21 +94,21 @@ func NewRepository(db *gorm.DB) Repository {
 }
 
 // UserExists checks if a user exists in the database.
-func (r *repositoryImpl) UserExists(username string) (bool, error) {
+func (r *repositoryImpl) UserExists(ctx context.Context, username string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("login = ?", username).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("login = ?", username).Count(&count).Error
 	return count > 0, err
 }
 
 // CreateUser saves a new user to the database.
-func (r *repositoryImpl) CreateUser(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *repositoryImpl) CreateUser(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
 // GetUserByLogin retrieves a user by their username.
-func (r *repositoryImpl) GetUserByLogin(username string) (*models.User, error) {
+func (r *repositoryImpl) GetUserByLogin(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("login = ?", username).First(&user).Error
+	err := r.db.WithContext(ctx).Where("login = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -52,26 +116,92 @@ func (r *repositoryImpl) GetUserByLogin(username string) (*models.User, error) {
 }
 
 // StoreData saves encrypted user data into the database.
-func (r *repositoryImpl) StoreData(entry *models.Vault) error {
-	return r.db.Create(entry).Error
+func (r *repositoryImpl) StoreData(ctx context.Context, entry *models.Vault) error {
+	return r.db.WithContext(ctx).Create(entry).Error
 }
 
-// RetrieveData fetches stored data of a given type for a user.
-func (r *repositoryImpl) RetrieveData(userID uint, dataType pb.DataType) ([]models.Vault, error) {
+// RetrieveData fetches the stored data of a user selected by query. Items
+// with the same time are ordered by ID, so cursors are unambiguous.
+func (r *repositoryImpl) RetrieveData(ctx context.Context, userID uint, query Query) ([]models.Vault, error) {
+	db := r.db.WithContext(ctx).Where("owner_id = ?", userID)
+	if len(query.Types) > 0 {
+		db = db.Where("data_type IN ?", query.Types)
+	}
+	if !query.ModifiedSince.IsZero() {
+		db = db.Where("modified_at >= ?", query.ModifiedSince.UTC())
+	}
+
+	column, desc := sortColumn(query.Sort)
+	op, direction := ">", "ASC"
+	if desc {
+		op, direction = "<", "DESC"
+	}
+	if query.After != nil {
+		after := query.After.Time.UTC()
+		db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, op), after, after, query.After.ID)
+	}
+	db = db.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction))
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	if query.HeadersOnly {
+		db = db.Select(headerColumns)
+	}
+
 	var entries []models.Vault
-	err := r.db.Where("owner_id = ? AND data_type = ?", userID, dataType).Find(&entries).Error
-	if err != nil {
+	if err := db.Find(&entries).Error; err != nil {
 		return nil, err
 	}
 	return entries, nil
 }
 
+// GetData fetches one item of a user.
+func (r *repositoryImpl) GetData(ctx context.Context, userID uint, id uint) (*models.Vault, error) {
+	var entry models.Vault
+	err := r.db.WithContext(ctx).Where("id = ? AND owner_id = ?", id, userID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
 // GetMasterSeed retrieves the encrypted master seed for a user.
-func (r *repositoryImpl) GetMasterSeed(userID uint) (string, error) {
+func (r *repositoryImpl) GetMasterSeed(ctx context.Context, userID uint) (string, error) {
 	var user models.User
-	err := r.db.Where("id = ?", userID).First(&user).Error
+	err := r.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error
 	if err != nil {
 		return "", err
 	}
 	return user.MasterSeed, nil
 }
+
+// Usage sums the items of a user, counting the bytes of their data and
+// metadata as the item size limit does. On PostgreSQL it locks the user's row
+// until the end of the transaction, so concurrent quota checks in WithTx
+// cannot both pass; SQLite serializes transactions already.
+func (r *repositoryImpl) Usage(ctx context.Context, userID uint) (Usage, error) {
+	db := r.db.WithContext(ctx)
+	if db.Dialector.Name() == "postgres" {
+		if err := db.Exec("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Error; err != nil {
+			return Usage{}, err
+		}
+	}
+
+	var usage Usage
+	err := db.Model(&models.Vault{}).
+		// OCTET_LENGTH counts bytes of text too, where LENGTH counts characters.
+		Select("COUNT(*) AS items, COALESCE(SUM(OCTET_LENGTH(data) + OCTET_LENGTH(metadata)), 0) AS bytes").
+		Where("owner_id = ?", userID).
+		Scan(&usage).Error
+	return usage, err
+}
+
+// WithTx runs fn in a database transaction.
+func (r *repositoryImpl) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&repositoryImpl{db: tx})
+	})
+}
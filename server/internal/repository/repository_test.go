@@ -1,167 +1,448 @@
 package repository_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	pb "github.com/golangTroshin/gophkeeper/grpc"
+	"github.com/golangTroshin/gophkeeper/server/internal/config"
+	"github.com/golangTroshin/gophkeeper/server/internal/database"
 	"github.com/golangTroshin/gophkeeper/server/internal/models"
 	"github.com/golangTroshin/gophkeeper/server/internal/repository"
-	"gorm.io/driver/sqlite"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var repo repository.Repository
-var testDB *gorm.DB
+// postgresDSNEnv names a disposable PostgreSQL database for the tests. Its
+// tables are emptied before every test.
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
 
-// setupTestDB initializes an in-memory SQLite database for testing.
-func setupTestDB(t *testing.T) {
-	var err error
-	testDB, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("Failed to create in-memory database: %v", err)
-	}
+// backend opens an empty, migrated database.
+type backend struct {
+	name string
+	open func(t *testing.T) *gorm.DB
+}
 
-	// Run migrations
-	err = testDB.AutoMigrate(&models.User{}, &models.Vault{})
-	if err != nil {
-		t.Fatalf("Database migration failed: %v", err)
-	}
+// backends lists the storage drivers every repository test runs against.
+var backends = []backend{
+	{"sqlite-memory", func(t *testing.T) *gorm.DB {
+		db, err := database.Open(config.Database{Driver: config.DriverSQLite, Path: database.MemoryPath})
+		if err != nil {
+			t.Fatalf("Failed to create in-memory database: %v", err)
+		}
+		t.Cleanup(func() { database.Close(db) })
+		return db
+	}},
+	{"sqlite-file", func(t *testing.T) *gorm.DB {
+		cfg := config.Database{Driver: config.DriverSQLite, Path: filepath.Join(t.TempDir(), "vault.db")}
+		db, err := database.Open(cfg)
+		if err != nil {
+			t.Fatalf("Failed to open SQLite database: %v", err)
+		}
+		t.Cleanup(func() { database.Close(db) })
+		return db
+	}},
+	{"postgres", func(t *testing.T) *gorm.DB {
+		dsn := os.Getenv(postgresDSNEnv)
+		if dsn == "" {
+			t.Skipf("%s is not set", postgresDSNEnv)
+		}
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("Failed to connect to PostgreSQL: %v", err)
+		}
+		if err := database.Migrate(db); err != nil {
+			t.Fatalf("Database migration failed: %v", err)
+		}
+		if err := db.Exec("TRUNCATE users, vaults RESTART IDENTITY").Error; err != nil {
+			t.Fatalf("Failed to empty the database: %v", err)
+		}
+		return db
+	}},
+}
 
-	// Create the repository instance
-	repo = repository.NewRepository(testDB)
+// forEachBackend runs test against a fresh repository of every backend.
+func forEachBackend(t *testing.T, test func(t *testing.T, repo repository.Repository)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			db := b.open(t)
+			if err := database.Migrate(db); err != nil {
+				t.Fatalf("Database migration failed: %v", err)
+			}
+			test(t, repository.NewRepository(db))
+		})
+	}
 }
 
 // TestUserExists ensures checking user existence works.
 func TestUserExists(t *testing.T) {
-	setupTestDB(t)
-
-	// Insert test user
-	testUser := models.User{
-		Login:      "testuser",
-		Password:   "hashedpassword",
-		MasterSeed: "testseed",
-	}
-	if err := repo.CreateUser(&testUser); err != nil {
-		t.Fatalf("Failed to create user: %v", err)
-	}
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		// Insert test user
+		testUser := models.User{
+			Login:      "testuser",
+			Password:   "hashedpassword",
+			MasterSeed: "testseed",
+		}
+		if err := repo.CreateUser(ctx, &testUser); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
 
-	// Check if the user exists
-	exists, err := repo.UserExists("testuser")
-	if err != nil {
-		t.Fatalf("Error checking user existence: %v", err)
-	}
-	if !exists {
-		t.Fatalf("Expected user to exist, but it does not")
-	}
+		// Check if the user exists
+		exists, err := repo.UserExists(ctx, "testuser")
+		if err != nil {
+			t.Fatalf("Error checking user existence: %v", err)
+		}
+		if !exists {
+			t.Fatalf("Expected user to exist, but it does not")
+		}
+	})
 }
 
 // TestCreateUser ensures a user is created successfully.
 func TestCreateUser(t *testing.T) {
-	setupTestDB(t)
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		testUser := models.User{
+			Login:      "newuser",
+			Password:   "hashedpassword",
+			MasterSeed: "newseed",
+		}
 
-	testUser := models.User{
-		Login:      "newuser",
-		Password:   "hashedpassword",
-		MasterSeed: "newseed",
-	}
+		err := repo.CreateUser(ctx, &testUser)
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
 
-	err := repo.CreateUser(&testUser)
-	if err != nil {
-		t.Fatalf("Failed to create user: %v", err)
-	}
-
-	// Verify user exists
-	exists, err := repo.UserExists("newuser")
-	if err != nil || !exists {
-		t.Fatalf("User creation failed: %v", err)
-	}
+		// Verify user exists
+		exists, err := repo.UserExists(ctx, "newuser")
+		if err != nil || !exists {
+			t.Fatalf("User creation failed: %v", err)
+		}
+	})
 }
 
 // TestGetUserByLogin ensures retrieving a user by login works.
 func TestGetUserByLogin(t *testing.T) {
-	setupTestDB(t)
-
-	testUser := models.User{
-		Login:      "lookupuser",
-		Password:   "hashedpassword",
-		MasterSeed: "seed123",
-	}
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		testUser := models.User{
+			Login:      "lookupuser",
+			Password:   "hashedpassword",
+			MasterSeed: "seed123",
+		}
 
-	err := repo.CreateUser(&testUser)
-	if err != nil {
-		t.Fatalf("Failed to create user: %v", err)
-	}
+		err := repo.CreateUser(ctx, &testUser)
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
 
-	user, err := repo.GetUserByLogin("lookupuser")
-	if err != nil {
-		t.Fatalf("Failed to get user by login: %v", err)
-	}
+		user, err := repo.GetUserByLogin(ctx, "lookupuser")
+		if err != nil {
+			t.Fatalf("Failed to get user by login: %v", err)
+		}
 
-	if user.Login != "lookupuser" {
-		t.Fatalf("Expected username 'lookupuser', got '%s'", user.Login)
-	}
+		if user.Login != "lookupuser" {
+			t.Fatalf("Expected username 'lookupuser', got '%s'", user.Login)
+		}
+	})
 }
 
 // TestStoreAndRetrieveData ensures data can be stored and retrieved correctly.
 func TestStoreAndRetrieveData(t *testing.T) {
-	setupTestDB(t)
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		// Create a test user
+		testUser := models.User{
+			Login:      "datauser",
+			Password:   "hashedpassword",
+			MasterSeed: "dataseed",
+		}
+		if err := repo.CreateUser(ctx, &testUser); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
 
-	// Create a test user
-	testUser := models.User{
-		Login:      "datauser",
-		Password:   "hashedpassword",
-		MasterSeed: "dataseed",
-	}
-	if err := repo.CreateUser(&testUser); err != nil {
-		t.Fatalf("Failed to create user: %v", err)
-	}
+		// Store test data
+		testEntry := models.Vault{
+			OwnerID:  uint(testUser.ID),
+			DataType: pb.DataType_TEXT,
+			Metadata: "Test Data",
+			Data:     []byte("Encrypted text"),
+		}
+		if err := repo.StoreData(ctx, &testEntry); err != nil {
+			t.Fatalf("Failed to store data: %v", err)
+		}
 
-	// Store test data
-	testEntry := models.Vault{
-		OwnerID:  uint(testUser.ID),
-		DataType: pb.DataType_TEXT,
-		Metadata: "Test Data",
-		Data:     []byte("Encrypted text"),
-	}
-	if err := repo.StoreData(&testEntry); err != nil {
-		t.Fatalf("Failed to store data: %v", err)
-	}
+		// Retrieve data
+		dataEntries, err := repo.RetrieveData(ctx, uint(testUser.ID), repository.Query{Types: []pb.DataType{pb.DataType_TEXT}})
+		if err != nil {
+			t.Fatalf("Failed to retrieve data: %v", err)
+		}
+		if len(dataEntries) == 0 {
+			t.Fatal("Expected at least one data entry, got none")
+		}
 
-	// Retrieve data
-	dataEntries, err := repo.RetrieveData(uint(testUser.ID), pb.DataType_TEXT)
-	if err != nil {
-		t.Fatalf("Failed to retrieve data: %v", err)
-	}
-	if len(dataEntries) == 0 {
-		t.Fatal("Expected at least one data entry, got none")
-	}
-
-	// Verify the stored data
-	if string(dataEntries[0].Data) != "Encrypted text" {
-		t.Fatalf("Stored data does not match, expected 'Encrypted text', got '%s'", string(dataEntries[0].Data))
-	}
+		// Verify the stored data
+		if string(dataEntries[0].Data) != "Encrypted text" {
+			t.Fatalf("Stored data does not match, expected 'Encrypted text', got '%s'", string(dataEntries[0].Data))
+		}
+	})
 }
 
 // TestGetMasterSeed ensures retrieving a user's master seed works.
 func TestGetMasterSeed(t *testing.T) {
-	setupTestDB(t)
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		testUser := models.User{
+			Login:      "seeduser",
+			Password:   "hashedpassword",
+			MasterSeed: "supersecretseed",
+		}
 
-	testUser := models.User{
-		Login:      "seeduser",
-		Password:   "hashedpassword",
-		MasterSeed: "supersecretseed",
-	}
+		if err := repo.CreateUser(ctx, &testUser); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
 
-	if err := repo.CreateUser(&testUser); err != nil {
-		t.Fatalf("Failed to create user: %v", err)
-	}
+		seed, err := repo.GetMasterSeed(ctx, uint(testUser.ID))
+		if err != nil {
+			t.Fatalf("Failed to retrieve master seed: %v", err)
+		}
 
-	seed, err := repo.GetMasterSeed(uint(testUser.ID))
-	if err != nil {
-		t.Fatalf("Failed to retrieve master seed: %v", err)
-	}
+		if seed != "supersecretseed" {
+			t.Fatalf("Expected master seed 'supersecretseed', got '%s'", seed)
+		}
+	})
+}
 
-	if seed != "supersecretseed" {
-		t.Fatalf("Expected master seed 'supersecretseed', got '%s'", seed)
-	}
+// TestCreateDuplicateUser ensures logins are unique on every backend.
+func TestCreateDuplicateUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		if err := repo.CreateUser(ctx, &models.User{Login: "twin", Password: "hash", MasterSeed: "seed"}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if err := repo.CreateUser(ctx, &models.User{Login: "twin", Password: "hash", MasterSeed: "seed"}); err == nil {
+			t.Fatal("Expected an error for a duplicate login")
+		}
+
+		if _, err := repo.GetUserByLogin(ctx, "nobody"); err == nil {
+			t.Fatal("Expected an error for an unknown login")
+		}
+		if _, err := repo.GetMasterSeed(ctx, 12345); err == nil {
+			t.Fatal("Expected an error for an unknown user")
+		}
+	})
+}
+
+// TestRetrieveDataIsolation ensures users only get their own data of the requested type.
+func TestRetrieveDataIsolation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		alice := models.User{Login: "alice", Password: "hash", MasterSeed: "seed"}
+		bob := models.User{Login: "bob", Password: "hash", MasterSeed: "seed"}
+		for _, user := range []*models.User{&alice, &bob} {
+			if err := repo.CreateUser(ctx, user); err != nil {
+				t.Fatalf("Failed to create user: %v", err)
+			}
+		}
+
+		entries := []models.Vault{
+			{OwnerID: uint(alice.ID), DataType: pb.DataType_TEXT, Metadata: "alice note", Data: []byte("a1")},
+			{OwnerID: uint(alice.ID), DataType: pb.DataType_CREDENTIALS, Metadata: "alice login", Data: []byte("a2")},
+			{OwnerID: uint(bob.ID), DataType: pb.DataType_TEXT, Metadata: "bob note", Data: []byte("b1")},
+		}
+		for i := range entries {
+			if err := repo.StoreData(ctx, &entries[i]); err != nil {
+				t.Fatalf("Failed to store data: %v", err)
+			}
+		}
+
+		notes, err := repo.RetrieveData(ctx, uint(alice.ID), repository.Query{Types: []pb.DataType{pb.DataType_TEXT}})
+		if err != nil {
+			t.Fatalf("Failed to retrieve data: %v", err)
+		}
+		if len(notes) != 1 || notes[0].Metadata != "alice note" {
+			t.Fatalf("Expected only alice's note, got %+v", notes)
+		}
+		if notes[0].CreatedAt.IsZero() {
+			t.Fatal("Expected the creation time to be set")
+		}
+
+		cards, err := repo.RetrieveData(ctx, uint(bob.ID), repository.Query{Types: []pb.DataType{pb.DataType_CARD}})
+		if err != nil {
+			t.Fatalf("Failed to retrieve data: %v", err)
+		}
+		if len(cards) != 0 {
+			t.Fatalf("Expected no cards, got %d", len(cards))
+		}
+	})
+}
+
+// TestWithTx ensures transactions commit on success and roll back on error.
+func TestWithTx(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		errAbort := errors.New("abort")
+
+		err := repo.WithTx(ctx, func(tx repository.Repository) error {
+			if err := tx.CreateUser(ctx, &models.User{Login: "rolledback", Password: "hash", MasterSeed: "seed"}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("Expected the error of the transaction, got %v", err)
+		}
+		if exists, _ := repo.UserExists(ctx, "rolledback"); exists {
+			t.Fatal("Expected the user to be rolled back")
+		}
+
+		err = repo.WithTx(ctx, func(tx repository.Repository) error {
+			return tx.CreateUser(ctx, &models.User{Login: "committed", Password: "hash", MasterSeed: "seed"})
+		})
+		if err != nil {
+			t.Fatalf("Transaction failed: %v", err)
+		}
+		if exists, _ := repo.UserExists(ctx, "committed"); !exists {
+			t.Fatal("Expected the user to be committed")
+		}
+	})
+}
+
+// TestCancelledContext ensures operations stop when their context is cancelled.
+func TestCancelledContext(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := repo.UserExists(ctx, "anyone"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if err := repo.CreateUser(ctx, &models.User{Login: "late", Password: "hash", MasterSeed: "seed"}); err == nil {
+			t.Fatal("Expected the cancelled insert to fail")
+		}
+		if exists, _ := repo.UserExists(context.Background(), "late"); exists {
+			t.Fatal("Expected the cancelled insert not to be stored")
+		}
+	})
+}
+
+// TestRetrieveDataQuery ensures items are filtered, sorted and paged by cursor.
+func TestRetrieveDataQuery(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		user := models.User{Login: "pager", Password: "hash", MasterSeed: "seed"}
+		if err := repo.CreateUser(ctx, &user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+
+		// Items are created an hour apart and modified in reverse order.
+		base := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+		types := []pb.DataType{pb.DataType_TEXT, pb.DataType_CARD, pb.DataType_TEXT, pb.DataType_OTP, pb.DataType_TEXT}
+		for i, dataType := range types {
+			entry := models.Vault{
+				OwnerID:    uint(user.ID),
+				DataType:   dataType,
+				Metadata:   fmt.Sprintf("item %d", i),
+				Data:       []byte("payload"),
+				CreatedAt:  base.Add(time.Duration(i) * time.Hour),
+				ModifiedAt: base.Add(time.Duration(10-i) * time.Hour),
+			}
+			if err := repo.StoreData(ctx, &entry); err != nil {
+				t.Fatalf("Failed to store data: %v", err)
+			}
+		}
+
+		names := func(entries []models.Vault) []string {
+			var result []string
+			for _, entry := range entries {
+				result = append(result, entry.Metadata)
+			}
+			return result
+		}
+		retrieve := func(query repository.Query) []models.Vault {
+			entries, err := repo.RetrieveData(ctx, uint(user.ID), query)
+			if err != nil {
+				t.Fatalf("Failed to retrieve data: %v", err)
+			}
+			return entries
+		}
+
+		page := retrieve(repository.Query{Sort: pb.SortOrder_CREATED_DESC, Limit: 2})
+		if got := names(page); !slices.Equal(got, []string{"item 4", "item 3"}) {
+			t.Fatalf("Unexpected first page %v", got)
+		}
+		after := repository.CursorOf(page[1], pb.SortOrder_CREATED_DESC)
+		page = retrieve(repository.Query{Sort: pb.SortOrder_CREATED_DESC, Limit: 2, After: &after})
+		if got := names(page); !slices.Equal(got, []string{"item 2", "item 1"}) {
+			t.Fatalf("Unexpected second page %v", got)
+		}
+
+		page = retrieve(repository.Query{Sort: pb.SortOrder_MODIFIED_ASC, Types: []pb.DataType{pb.DataType_TEXT}})
+		if got := names(page); !slices.Equal(got, []string{"item 4", "item 2", "item 0"}) {
+			t.Fatalf("Unexpected text items by modification %v", got)
+		}
+
+		page = retrieve(repository.Query{ModifiedSince: base.Add(9 * time.Hour), Types: []pb.DataType{pb.DataType_TEXT, pb.DataType_CARD}})
+		if got := names(page); !slices.Equal(got, []string{"item 0", "item 1"}) {
+			t.Fatalf("Unexpected recently modified items %v", got)
+		}
+
+		page = retrieve(repository.Query{HeadersOnly: true})
+		if len(page) != len(types) || page[0].Data != nil || page[0].Metadata == "" || page[0].Size != int64(len("payload")) {
+			t.Fatalf("Expected headers without data, got %+v", page[0])
+		}
+
+		entry, err := repo.GetData(ctx, uint(user.ID), page[0].ID)
+		if err != nil || string(entry.Data) != "payload" {
+			t.Fatalf("Expected the full item, got %+v, %v", entry, err)
+		}
+		if _, err := repo.GetData(ctx, uint(user.ID)+1, page[0].ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound for another user's item, got %v", err)
+		}
+	})
+}
+
+// TestUsage ensures the items and bytes of each user are counted separately.
+func TestUsage(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo repository.Repository) {
+		ctx := context.Background()
+		alice := models.User{Login: "alice", Password: "hash", MasterSeed: "seed"}
+		bob := models.User{Login: "bob", Password: "hash", MasterSeed: "seed"}
+		for _, user := range []*models.User{&alice, &bob} {
+			if err := repo.CreateUser(ctx, user); err != nil {
+				t.Fatalf("Failed to create user: %v", err)
+			}
+		}
+
+		usage, err := repo.Usage(ctx, uint(alice.ID))
+		if err != nil || usage != (repository.Usage{}) {
+			t.Fatalf("Expected no usage, got %+v, %v", usage, err)
+		}
+
+		for _, size := range []int{100, 250} {
+			entry := models.Vault{OwnerID: uint(alice.ID), DataType: pb.DataType_BINARY, Metadata: "fïle", Data: make([]byte, size)}
+			if err := repo.StoreData(ctx, &entry); err != nil {
+				t.Fatalf("Failed to store data: %v", err)
+			}
+		}
+		if err := repo.StoreData(ctx, &models.Vault{OwnerID: uint(bob.ID), DataType: pb.DataType_TEXT, Metadata: "note", Data: make([]byte, 10)}); err != nil {
+			t.Fatalf("Failed to store data: %v", err)
+		}
+
+		err = repo.WithTx(ctx, func(tx repository.Repository) error {
+			usage, err = tx.Usage(ctx, uint(alice.ID))
+			return err
+		})
+		// Metadata counts in bytes, "fïle" is 5 of them.
+		if err != nil || usage != (repository.Usage{Items: 2, Bytes: 360}) {
+			t.Fatalf("Expected 2 items of 360 bytes, got %+v, %v", usage, err)
+		}
+	})
 }
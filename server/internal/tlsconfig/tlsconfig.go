@@ -0,0 +1,167 @@
+// Package tlsconfig builds the TLS configuration of the gRPC server.
+//
+// Certificates are read from PEM files and reloaded when the files change,
+// so renewed certificates are picked up without restarting the server.
+// Client certificates can optionally be requested or required to
+// authenticate devices (mutual TLS).
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client authentication modes.
+const (
+	ClientAuthNone    = "none"    // Do not ask for client certificates
+	ClientAuthRequest = "request" // Verify client certificates when presented
+	ClientAuthRequire = "require" // Reject clients without a valid certificate
+)
+
+// Options locate the certificate files.
+type Options struct {
+	CertFile     string // Server certificate chain
+	KeyFile      string // Private key of the server certificate
+	ClientCAFile string // CAs issuing client certificates, required unless ClientAuth is none
+	ClientAuth   string // One of the ClientAuth modes, none if empty
+}
+
+// Reloader serves the current certificates, reloading them when their
+// files are modified.
+type Reloader struct {
+	opts       Options
+	clientAuth tls.ClientAuthType
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// New loads the certificates. Errors here are fatal, later reload errors
+// keep the previous certificates.
+func New(opts Options) (*Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, fmt.Errorf("both a certificate and a key file are required")
+	}
+
+	r := &Reloader{opts: opts}
+	switch opts.ClientAuth {
+	case "", ClientAuthNone:
+		r.clientAuth = tls.NoClientCert
+	case ClientAuthRequest:
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		r.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q, use %s, %s or %s", opts.ClientAuth, ClientAuthNone, ClientAuthRequest, ClientAuthRequire)
+	}
+	if r.clientAuth != tls.NoClientCert && opts.ClientCAFile == "" {
+		return nil, fmt.Errorf("client auth mode %q requires a client CA file", opts.ClientAuth)
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Config returns a server configuration using the current certificates for
+// every handshake.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.reloadIfChanged()
+
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.clientCAs,
+			}, nil
+		},
+	}
+}
+
+// Reload reads the certificate files, e.g. on SIGHUP. The previous
+// certificates are kept if the files are invalid.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: no PEM certificates found", r.opts.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	return nil
+}
+
+// reloadIfChanged reloads the certificates if a file was modified since the
+// last load. Failures are logged and the old certificates stay in use.
+func (r *Reloader) reloadIfChanged() {
+	modTimes, err := r.stat()
+	if err != nil {
+		log.Printf("Failed to check TLS certificates: %v", err)
+		return
+	}
+
+	r.mu.Lock()
+	changed := false
+	for path, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[path]) {
+			changed = true
+		}
+	}
+	r.mu.Unlock()
+
+	if changed {
+		if err := r.Reload(); err != nil {
+			log.Printf("Failed to reload TLS certificates, keeping the previous ones: %v", err)
+			return
+		}
+		log.Println("TLS certificates reloaded.")
+	}
+}
+
+// stat returns the modification times of the certificate files.
+func (r *Reloader) stat() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time, 3)
+	for _, path := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[path] = info.ModTime()
+	}
+	return modTimes, nil
+}
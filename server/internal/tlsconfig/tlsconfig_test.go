@@ -0,0 +1,196 @@
+package tlsconfig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golangTroshin/gophkeeper/server/internal/tlsconfig"
+)
+
+// issued is a certificate with its key.
+type issued struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// issue creates a certificate for name signed by parent, or self-signed if parent is nil.
+func issue(t *testing.T, name string, parent *issued, isCA bool) *issued {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &issued{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeFile writes data and moves the modification time forward so changes are detected.
+func writeFile(t *testing.T, path string, data []byte, mod time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatalf("Failed to set times of %s: %v", path, err)
+	}
+}
+
+// handshake connects a client to a server using serverCfg and returns the
+// common name of the server certificate.
+func handshake(t *testing.T, serverCfg *tls.Config, clientCfg *tls.Config) (string, error) {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Confirm the handshake so the client learns whether its certificate was accepted.
+		if err := conn.(*tls.Conn).Handshake(); err == nil {
+			_, _ = conn.Write([]byte("x"))
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientCfg)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// With TLS 1.3 a rejected client certificate is only reported after the handshake.
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		return "", err
+	}
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+// TestReload ensures renewed certificates are served without restarting
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "Test CA", nil, true)
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+
+	first := issue(t, "first.test", ca, false)
+	mod := time.Now().Add(-time.Minute)
+	writeFile(t, certFile, first.certPEM, mod)
+	writeFile(t, keyFile, first.keyPEM, mod)
+
+	r, err := tlsconfig.New(tlsconfig.Options{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	name, err := handshake(t, r.Config(), &tls.Config{RootCAs: roots, ServerName: "first.test"})
+	if err != nil || name != "first.test" {
+		t.Fatalf("Expected first.test, got %q, %v", name, err)
+	}
+
+	second := issue(t, "second.test", ca, false)
+	writeFile(t, certFile, second.certPEM, time.Now())
+	writeFile(t, keyFile, second.keyPEM, time.Now())
+
+	name, err = handshake(t, r.Config(), &tls.Config{RootCAs: roots, ServerName: "second.test"})
+	if err != nil || name != "second.test" {
+		t.Fatalf("Expected the renewed certificate, got %q, %v", name, err)
+	}
+
+	writeFile(t, certFile, []byte("broken"), time.Now().Add(time.Minute))
+	name, err = handshake(t, r.Config(), &tls.Config{RootCAs: roots, ServerName: "second.test"})
+	if err != nil || name != "second.test" {
+		t.Fatalf("Invalid files should keep the previous certificate, got %q, %v", name, err)
+	}
+}
+
+// TestClientAuth ensures clients without a certificate from the client CA are rejected
+func TestClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "Test CA", nil, true)
+	deviceCA := issue(t, "Device CA", nil, true)
+	server := issue(t, "server.test", ca, false)
+	mod := time.Now()
+	writeFile(t, filepath.Join(dir, "server.crt"), server.certPEM, mod)
+	writeFile(t, filepath.Join(dir, "server.key"), server.keyPEM, mod)
+	writeFile(t, filepath.Join(dir, "devices.crt"), deviceCA.certPEM, mod)
+
+	opts := tlsconfig.Options{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "devices.crt"),
+		ClientAuth:   tlsconfig.ClientAuthRequire,
+	}
+	r, err := tlsconfig.New(opts)
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	if _, err := handshake(t, r.Config(), &tls.Config{RootCAs: roots, ServerName: "server.test"}); err == nil {
+		t.Fatalf("Expected clients without a certificate to be rejected")
+	}
+
+	device := issue(t, "laptop", deviceCA, false)
+	cert, err := tls.X509KeyPair(device.certPEM, device.keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load client certificate: %v", err)
+	}
+	clientCfg := &tls.Config{RootCAs: roots, ServerName: "server.test", Certificates: []tls.Certificate{cert}}
+	if _, err := handshake(t, r.Config(), clientCfg); err != nil {
+		t.Fatalf("Expected the device certificate to be accepted: %v", err)
+	}
+
+	opts.ClientCAFile = ""
+	if _, err := tlsconfig.New(opts); err == nil {
+		t.Fatalf("Expected an error without a client CA")
+	}
+	opts.ClientAuth = "maybe"
+	if _, err := tlsconfig.New(opts); err == nil {
+		t.Fatalf("Expected an error for an unknown client auth mode")
+	}
+}